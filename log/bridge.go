@@ -0,0 +1,203 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+//a longer line without the newline is written in pieces
+const bridge_max_line = 64 * KB
+
+//the lines a handler writes to a bridge writer go to bridgeFallback,
+//logging them again would never end
+var bridgeFallback io.Writer = os.Stderr
+
+//lineWriter calls write with every line written to it without the newline,
+//a line is kept until its newline is written
+type lineWriter struct {
+	mu    sync.Mutex
+	buf   []byte
+	write func(line string, pc uintptr)
+}
+
+func newLineWriter(write func(line string, pc uintptr)) *lineWriter {
+	return &lineWriter{write: write}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	pc, recursive := bridgeCaller()
+	if recursive {
+		return bridgeFallback.Write(p)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i], pc)
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= bridge_max_line {
+		w.writeLine(w.buf, pc)
+		w.buf = w.buf[:0]
+	}
+	if len(w.buf) == 0 {
+		//release a large buffer
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) writeLine(line []byte, pc uintptr) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) > 0 {
+		w.write(string(line), pc)
+	}
+}
+
+//the functions bridgeCaller looks for
+var lineWriterWrite, loggerDispatch string
+
+func init() {
+	lineWriterWrite = funcName((*lineWriter).Write)
+	loggerDispatch = funcName((*LoggerImp).dispatch)
+}
+
+func funcName(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+//isWriterFunc returns true if f is of the standard packages which
+//write to a writer for their callers like log.Printf or fmt.Fprintf
+func isWriterFunc(f string) bool {
+	for _, pkg := range []string{"log.", "fmt.", "io.", "bufio."} {
+		if strings.HasPrefix(f, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+//bridgeCaller returns the caller writing to the lineWriter, skipping the
+//standard packages writing for it, recursive is true if it is called by a handler
+//of a logger or by lineWriter itself, like a slog handler writing to
+//the standard logger redirected to its logger
+func bridgeCaller() (pc uintptr, recursive bool) {
+	//the whole stack, a handler may be many frames below dispatch
+	var pcs [max_stack_depth]uintptr
+	//skip runtime.Callers, bridgeCaller and lineWriter.Write
+	n := runtime.Callers(3, pcs[:])
+	for _, p := range pcs[:n] {
+		switch bridgeFrameOf(p) {
+		case bridgeRecursiveFrame:
+			return 0, true
+		case bridgeCallerFrame:
+			if pc == 0 {
+				pc = p
+			}
+		}
+	}
+	return pc, false
+}
+
+//bridgeFrame is the kind of a frame above lineWriter.Write
+type bridgeFrame uint8
+
+const (
+	bridgeCallerFrame    bridgeFrame = iota //of the caller
+	bridgeWriterFrame                       //of the standard packages writing for the caller
+	bridgeRecursiveFrame                    //of lineWriter.Write or dispatch
+)
+
+//the kinds of the return addresses seen, symbolizing them on every write is slow
+var (
+	bridgeFrameLock sync.RWMutex
+	bridgeFrames    = make(map[uintptr]bridgeFrame)
+)
+
+//bridgeFrameOf returns the kind of the return address pc, a pc of
+//inlined calls is of the caller if any of them is
+func bridgeFrameOf(pc uintptr) bridgeFrame {
+	bridgeFrameLock.RLock()
+	kind, ok := bridgeFrames[pc]
+	bridgeFrameLock.RUnlock()
+	if ok {
+		return kind
+	}
+	kind = bridgeWriterFrame
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if frame.Function == lineWriterWrite || frame.Function == loggerDispatch {
+			kind = bridgeRecursiveFrame
+			break
+		}
+		if !isWriterFunc(frame.Function) {
+			kind = bridgeCallerFrame
+		}
+		if !more {
+			break
+		}
+	}
+	bridgeFrameLock.Lock()
+	bridgeFrames[pc] = kind
+	bridgeFrameLock.Unlock()
+	return kind
+}
+
+//Writer returns a writer logging every line written to it at level,
+//see NewStdLogger and RedirectStdLog
+func (l *LoggerImp) Writer(level int) io.Writer {
+	return newLineWriter(func(line string, pc uintptr) {
+		l.writeLine(level, line, pc)
+	})
+}
+
+//writeLine logs line as it is, unlike writeMesg it is not a format
+func (l *LoggerImp) writeLine(level int, line string, pc uintptr) {
+	if l.moduleLevel() > level {
+		return
+	}
+	suppressed, ok := l.sample(level, line)
+	if !ok {
+		return
+	}
+	lm := l.newMesg(level, time.Now(), pc, line, nil, nil)
+	lm.suppressed = suppressed
+	l.enqueue(lm)
+}
+
+//NewStdLogger returns a standard logger writing to logger at level,
+//e.g. for http.Server.ErrorLog
+func NewStdLogger(logger Logger, level int) *log.Logger {
+	return log.New(logger.Writer(level), "", 0)
+}
+
+//RedirectStdLog makes the standard logger write to logger at level,
+//the time and prefix are left to logger, restore sets the output,
+//flags and prefix back, a handler writing to the standard logger
+//meanwhile writes to stderr instead, logger must not be a slog logger
+//whose handler writes to the standard logger synchronously like the
+//default slog handler, which deadlocks in the standard logger
+func RedirectStdLog(logger Logger, level int) (restore func()) {
+	std := log.Default()
+	flags, prefix, out := std.Flags(), std.Prefix(), std.Writer()
+	std.SetFlags(0)
+	std.SetPrefix("")
+	std.SetOutput(logger.Writer(level))
+	return func() {
+		std.SetOutput(out)
+		std.SetPrefix(prefix)
+		std.SetFlags(flags)
+	}
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	w := logger.Writer(WarnLevel)
+	io.WriteString(w, "a 100%d\nb")
+	io.WriteString(w, "c\r\n\n")
+	io.WriteString(w, strings.Repeat("x", bridge_max_line))
+	logger.Flush()
+	if len(h.mesgs) != 3 {
+		t.Fatal("messages expect 3 but is", len(h.mesgs))
+	}
+	for i, mesg := range []string{"a 100%d", "bc", strings.Repeat("x", bridge_max_line)} {
+		if lm := h.mesgs[i]; lm.Level != WarnLevel || lm.Mesg != mesg {
+			t.Errorf("message %d is %s %.20q", i, LevelName(lm.Level), lm.Mesg)
+		}
+	}
+
+	NewStdLogger(logger, ErrorLevel).Printf("std %s", "logger")
+	logger.Flush()
+	if lm := h.last(); lm.Mesg != "std logger" || lm.Level != ErrorLevel ||
+		lm.Caller().Function != "github.com/Hacky-DH/goLib/log.TestWriter" {
+		t.Errorf("message is %+v called by %s", lm, lm.Caller().Function)
+	}
+}
+
+//stdlogHandler writes every message to the standard logger
+type stdlogHandler struct {
+	recordHandler
+}
+
+func (h *stdlogHandler) Write(lm *LogMesg) {
+	h.recordHandler.Write(lm)
+	stdlog.Print("handler got ", lm.Mesg)
+}
+
+//deepStdlogHandler writes every message to the standard logger
+//through a deep call chain like a client library
+type deepStdlogHandler struct {
+	recordHandler
+}
+
+func (h *deepStdlogHandler) Write(lm *LogMesg) {
+	h.recordHandler.Write(lm)
+	deepPrint(40, lm.Mesg)
+}
+
+func deepPrint(depth int, mesg string) {
+	if depth == 0 {
+		stdlog.Print("deep handler got ", mesg)
+		return
+	}
+	deepPrint(depth-1, mesg)
+}
+
+func TestRedirectStdLogDeep(t *testing.T) {
+	var fallback bytes.Buffer
+	bridgeFallback = &fallback
+	defer func() { bridgeFallback = os.Stderr }()
+
+	logger, _ := newRecordLogger(t, false)
+	restore := RedirectStdLog(logger, InfoLevel)
+	defer restore()
+	sh := new(deepStdlogHandler)
+	logger.AddHandler("stdlog", sh)
+	stdlog.Print("loop")
+	logger.Flush()
+	if n := len(sh.mesgs); n != 1 {
+		t.Error("messages of the handler expect 1 but is", n)
+	}
+	if fallback.String() != "deep handler got loop\n" {
+		t.Errorf("fallback is %q", fallback.String())
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var fallback bytes.Buffer
+	bridgeFallback = &fallback
+	defer func() { bridgeFallback = os.Stderr }()
+	out := stdlog.Writer()
+
+	logger, h := newRecordLogger(t, false)
+	restore := RedirectStdLog(logger, InfoLevel)
+	stdlog.Print("hello")
+	logger.Flush()
+	if lm := h.last(); lm.Mesg != "hello" || lm.Level != InfoLevel {
+		t.Error("message is", lm)
+	}
+
+	//a handler writing to the standard logger is not logged again
+	sh := new(stdlogHandler)
+	logger.AddHandler("stdlog", sh)
+	stdlog.Print("loop")
+	logger.Flush()
+	if n := len(sh.mesgs); n != 1 {
+		t.Error("messages of the handler expect 1 but is", n)
+	}
+	if fallback.String() != "handler got loop\n" {
+		t.Errorf("fallback is %q", fallback.String())
+	}
+	restore()
+	if stdlog.Writer() != out || stdlog.Flags() != stdlog.LstdFlags {
+		t.Error("standard logger is not restored")
+	}
+
+}
+
+func BenchmarkWriter(b *testing.B) {
+	logger := NewLogger()
+	defer logger.Close(context.Background())
+	std := NewStdLogger(logger, InfoLevel)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		std.Print("hello")
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const max_stack_depth = 64
+
+//StackHandler is implemented by handlers writing stack traces,
+//the logger captures stacks only if one of its handlers wants them
+type StackHandler interface {
+	WantStack() bool
+}
+
+//WithStackLevel sets the level from which the goroutine stack is captured,
+//default ErrorLevel, messages with an error having a stack always carry it
+func WithStackLevel(level int) Option {
+	return func(c *loggerCore) {
+		c.stackLevel = level
+	}
+}
+
+//Caller returns the location lm is logged at,
+//the frame is empty if it is unknown
+func (lm *LogMesg) Caller() runtime.Frame {
+	if lm.PC == 0 {
+		return runtime.Frame{}
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{lm.PC}).Next()
+	return frame
+}
+
+//callerPC returns the pc of the frame skip frames above the caller of callerPC,
+//0 is the caller itself
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	//skip runtime.Callers and callerPC
+	runtime.Callers(skip+2, pcs[:])
+	return pcs[0]
+}
+
+//goroutineStack returns the stack of the current goroutine
+//starting at the frame of pc, pc 0 means the caller of goroutineStack
+func goroutineStack(pc uintptr) string {
+	var pcs [max_stack_depth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	stack := pcs[:n]
+	for i, p := range stack {
+		if p == pc {
+			stack = stack[i:]
+			break
+		}
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			b.WriteString(frame.Function)
+			b.WriteString("\n\t")
+			b.WriteString(frame.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+			b.WriteByte('\n')
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+//stackTracer is an error with a stack of program counters
+type stackTracer interface {
+	StackTrace() []uintptr
+}
+
+//errorStack returns the stack of the first error with a stack in args and fields,
+//an error has a stack if it or an error it wraps is a stackTracer or prints
+//more by %+v than Error like github.com/pkg/errors, the stack is printed by %+v
+func errorStack(args []interface{}, fields []Field) string {
+	for _, arg := range args {
+		if stack := stackOf(arg); stack != "" {
+			return stack
+		}
+	}
+	for _, field := range fields {
+		if stack := stackOf(field.Value); stack != "" {
+			return stack
+		}
+	}
+	return ""
+}
+
+func stackOf(v interface{}) string {
+	if field, ok := v.(Field); ok {
+		v = field.Value
+	}
+	err, ok := v.(error)
+	if !ok {
+		return ""
+	}
+	for ; err != nil; err = errors.Unwrap(err) {
+		if _, ok := err.(stackTracer); ok {
+			return fmt.Sprintf("%+v", err)
+		}
+		if _, ok := err.(fmt.Formatter); ok {
+			if s := fmt.Sprintf("%+v", err); s != err.Error() {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+//shortFile returns the last dir and the file name of path
+func shortFile(path string) string {
+	dir, file := filepath.Split(path)
+	return filepath.Join(filepath.Base(dir), file)
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//recordHandler keeps the messages, it wants stacks if stack is set
+type recordHandler struct {
+	mu    sync.Mutex
+	stack bool
+	mesgs []*LogMesg
+}
+
+func (h *recordHandler) Setup(config map[string]interface{}) error { return nil }
+func (h *recordHandler) Write(lm *LogMesg) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mesgs = append(h.mesgs, lm)
+}
+func (h *recordHandler) Rotate()         {}
+func (h *recordHandler) Flush() error    { return nil }
+func (h *recordHandler) Close() error    { return nil }
+func (h *recordHandler) WantStack() bool { return h.stack }
+func (h *recordHandler) last() *LogMesg {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.mesgs[len(h.mesgs)-1]
+}
+
+func newRecordLogger(t *testing.T, stack bool) (*LoggerImp, *recordHandler) {
+	logger := NewLogger().(*LoggerImp)
+	t.Cleanup(func() { logger.Close(context.Background()) })
+	h := &recordHandler{stack: stack}
+	logger.AddHandler("record", h)
+	return logger, h
+}
+
+//line returns the line of its caller
+func line() int {
+	_, _, n, _ := runtime.Caller(1)
+	return n
+}
+
+func TestCaller(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	sub := logger.Named("sub").With("k", 1)
+	sh, err := NewSlogHandler(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slogger := slog.New(sh)
+	cases := []struct {
+		name string
+		log  func() int
+	}{
+		{"logger", func() int { logger.Info("hello"); return line() }},
+		{"sub", func() int { sub.Warn("hello %s", "x"); return line() }},
+		{"slog", func() int { slogger.Info("hello"); return line() }},
+	}
+	for _, c := range cases {
+		n := c.log()
+		logger.Flush()
+		frame := h.last().Caller()
+		if !strings.HasSuffix(frame.File, "caller_test.go") || frame.Line != n {
+			t.Errorf("%s: caller is %s:%d, expect caller_test.go:%d", c.name, frame.File, frame.Line, n)
+		}
+		if !strings.Contains(frame.Function, "TestCaller") {
+			t.Errorf("%s: caller function is %s", c.name, frame.Function)
+		}
+		if h.last().Stack != "" {
+			t.Errorf("%s: no handler wants stacks but got one", c.name)
+		}
+	}
+}
+
+//stackError is an error with a stack like github.com/pkg/errors
+type stackError struct{ mesg string }
+
+func (e *stackError) Error() string         { return e.mesg }
+func (e *stackError) StackTrace() []uintptr { return nil }
+func (e *stackError) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, e.mesg)
+	if s.Flag('+') {
+		fmt.Fprint(s, "\nerror stack")
+	}
+}
+
+//frameError has a stack of a type of its own like github.com/pkg/errors
+type frameError struct{ mesg string }
+
+func (e *frameError) Error() string { return e.mesg }
+func (e *frameError) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, e.mesg)
+	if s.Flag('+') {
+		fmt.Fprint(s, "\nframe stack")
+	}
+}
+
+func TestStack(t *testing.T) {
+	logger, h := newRecordLogger(t, true)
+
+	logger.Info("no stack")
+	logger.Flush()
+	if stack := h.last().Stack; stack != "" {
+		t.Errorf("info has a stack:\n%s", stack)
+	}
+
+	logger.Error("failed")
+	logger.Flush()
+	stack := h.last().Stack
+	if !strings.HasPrefix(stack, "github.com/Hacky-DH/goLib/log.TestStack\n\t") ||
+		!strings.Contains(stack, "caller_test.go:") {
+		t.Errorf("error stack does not start at the caller:\n%s", stack)
+	}
+
+	err := fmt.Errorf("wrapped: %w", &stackError{"boom"})
+	logger.Info("failed", "err", err)
+	logger.Flush()
+	if stack := h.last().Stack; stack != "boom\nerror stack" {
+		t.Errorf("stack of the error is %q", stack)
+	}
+	logger.Info("failed", "err", fmt.Errorf("wrapped: %w", &frameError{"boom"}))
+	logger.Flush()
+	if stack := h.last().Stack; stack != "boom\nframe stack" {
+		t.Errorf("stack of the formatter error is %q", stack)
+	}
+	logger.Warn("failed: %v", errors.New("plain"))
+	logger.Flush()
+	if stack := h.last().Stack; stack != "" {
+		t.Errorf("error without stack gives a stack:\n%s", stack)
+	}
+
+	//no stack is captured once no handler wants it
+	logger.AddHandler("record", &recordHandler{})
+	if logger.wantStack.Load() {
+		t.Error("stack is wanted after the handler is replaced")
+	}
+}
+
+func TestEncodeCaller(t *testing.T) {
+	lm := testMesg()
+	lm.PC = callerPC(0)
+	n := line() - 1
+	lm.Stack = "main.main\n\tmain.go:1"
+	caller := fmt.Sprintf("log/caller_test.go:%d", n)
+
+	enc, _ := newEncoder("text", encodeOptions{caller: true, stack: true})
+	var buf bytes.Buffer
+	enc.encode(&buf, lm)
+	if !strings.Contains(buf.String(), "[WARN] "+caller+" disk <full>") ||
+		!strings.HasSuffix(buf.String(), "\nmain.main\n\tmain.go:1") {
+		t.Errorf("text with caller is\n%s", buf.String())
+	}
+
+	enc, _ = newEncoder("json", encodeOptions{caller: true})
+	buf.Reset()
+	enc.encode(&buf, lm)
+	if !strings.Contains(buf.String(), `"caller":"`+caller+`","func":"github.com/Hacky-DH/goLib/log.TestEncodeCaller"`) ||
+		strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("json with caller is\n%s", buf.String())
+	}
+}
@@ -0,0 +1,149 @@
+//Command logq reads the current and rotated files of a log file in order,
+//filters the records and follows the file like tail -F
+//
+//	logq -since 1h -level warn -grep timeout /var/log/app.log
+//	logq -f -json -e 'user=\d+' /var/log/app.log
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/Hacky-DH/goLib/log"
+	"github.com/Hacky-DH/goLib/log/logq"
+)
+
+//time layouts accepted by -since and -until besides a duration
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+//parseTime parses a time or a duration before now like 1h30m
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("bad time " + s)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: logq [flags] path\n\n"+
+		"read the log file path and its rotated files, the oldest first\n\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	since := flag.String("since", "", "the earliest time like 2006-01-02 15:04:05 or a duration before now like 1h")
+	until := flag.String("until", "", "the time to stop before, like -since")
+	level := flag.String("level", "trace", "the lowest level")
+	contains := flag.String("grep", "", "substring of the records")
+	expr := flag.String("e", "", "regexp of the records")
+	follow := flag.Bool("f", false, "wait for new records, following the file across rotations")
+	interval := flag.Duration("interval", time.Second, "how often to check the followed file")
+	asJSON := flag.Bool("json", false, "write the records as json lines")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	filter, err := newFilter(*since, *until, *level, *contains, *expr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logq:", err)
+		os.Exit(2)
+	}
+	r, err := logq.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logq:", err)
+		os.Exit(1)
+	}
+	defer r.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *follow {
+		r.Follow(ctx, *interval)
+	}
+	if err := run(r, &filter, os.Stdout, *asJSON, *follow); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "logq:", err)
+		os.Exit(1)
+	}
+}
+
+func newFilter(since, until, level, contains, expr string) (logq.Filter, error) {
+	f := logq.DefaultFilter()
+	now := time.Now()
+	var err error
+	if f.Since, err = parseTime(since, now); err != nil {
+		return f, err
+	}
+	if f.Until, err = parseTime(until, now); err != nil {
+		return f, err
+	}
+	if f.Level, err = log.ParseLevel(level); err != nil {
+		return f, err
+	}
+	f.Contains = contains
+	if expr != "" {
+		if f.Regexp, err = regexp.Compile(expr); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+//run writes the records matching f, flushing after each one if following
+func run(r *logq.Reader, f *logq.Filter, out io.Writer, asJSON, follow bool) error {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !f.Match(rec) {
+			continue
+		}
+		if asJSON {
+			b, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			w.Write(b)
+		} else {
+			w.WriteString(rec.Raw)
+		}
+		w.WriteByte('\n')
+		if follow {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+//ANSI escape codes
+const (
+	color_reset   = "\x1b[0m"
+	color_red     = "\x1b[31m"
+	color_green   = "\x1b[32m"
+	color_yellow  = "\x1b[33m"
+	color_blue    = "\x1b[34m"
+	color_magenta = "\x1b[35m"
+	color_cyan    = "\x1b[36m"
+	color_gray    = "\x1b[90m"
+)
+
+func levelColor(level int) string {
+	switch {
+	case level <= TraceLevel:
+		return color_gray
+	case level == DebugLevel:
+		return color_blue
+	case level == InfoLevel:
+		return color_green
+	case level == WarnLevel:
+		return color_yellow
+	case level == ErrorLevel:
+		return color_red
+	default:
+		return color_magenta
+	}
+}
+
+//useColor decides if the output to f is colored by mode auto, always or never,
+//auto colors a terminal unless the environment variable NO_COLOR is set
+func useColor(mode string, f *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+//isTerminal returns false if f is piped or redirected to a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func checkColorMode(mode string) error {
+	switch mode {
+	case "", "auto", "always", "never":
+		return nil
+	default:
+		return errors.New("color must be auto, always or never")
+	}
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUseColor(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	t.Setenv("NO_COLOR", "")
+	if useColor("auto", w) || useColor("", w) {
+		t.Error("auto colors a pipe")
+	}
+	if !useColor("always", w) || useColor("never", w) {
+		t.Error("color mode always/never error")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor("auto", os.Stdout) {
+		t.Error("auto colors with NO_COLOR")
+	}
+	if err := checkColorMode("rainbow"); err == nil {
+		t.Error("unknown color mode expect error")
+	}
+}
+
+func TestTextEncoderColor(t *testing.T) {
+	lm := testMesg()
+	enc, _ := newEncoder("text", encodeOptions{
+		timeLayout: timeLayout("millis"),
+		color:      true,
+		align:      true,
+	})
+	var buf bytes.Buffer
+	enc.encode(&buf, lm)
+	expect := "2020-01-02 03:04:05.006 " + color_yellow + "[WARN]" + color_reset + " disk <full>" +
+		strings.Repeat(" ", text_align_width-len("disk <full>")) +
+		" " + color_cyan + "path" + color_reset + `="/data/a b"` +
+		" " + color_cyan + "used" + color_reset + "=0.95" +
+		" " + color_cyan + "err" + color_reset + `="no space"`
+	if buf.String() != expect {
+		t.Errorf("colored text expect\n%q\nbut is\n%q", expect, buf.String())
+	}
+
+	local := time.FixedZone("UTC+8", 8*3600)
+	lm.Time = time.Date(2020, 1, 2, 11, 4, 5, 0, local)
+	lm.Fields = nil
+	enc, _ = newEncoder("text", encodeOptions{timeLayout: timeLayout("rfc3339"), utc: true, align: true})
+	buf.Reset()
+	enc.encode(&buf, lm)
+	if expect := "2020-01-02T03:04:05Z [WARN] disk <full>"; buf.String() != expect {
+		t.Errorf("utc text expect\n%q\nbut is\n%q", expect, buf.String())
+	}
+}
+
+func TestConsoleStderr(t *testing.T) {
+	h, err := NewConsoleHandler(ConsoleConfig{Stderr: true, Color: "never", TimeFormat: "15:04"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	ch := h.(*consoleHandler)
+	ch.stdout.logger.SetOutput(stdout)
+	ch.stderr.logger.SetOutput(stderr)
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	for level := DebugLevel; level <= ErrorLevel; level++ {
+		h.Write(&LogMesg{Level: level, Time: at, Mesg: LevelName(level)})
+	}
+	if s := stdout.String(); s != "03:04 [DEBUG] DEBUG\n03:04 [INFO] INFO\n" {
+		t.Errorf("stdout is %q", s)
+	}
+	if s := stderr.String(); s != "03:04 [WARN] WARN\n03:04 [ERROR] ERROR\n" {
+		t.Errorf("stderr is %q", s)
+	}
+
+	if _, err := NewConsoleHandler(ConsoleConfig{Color: "rainbow"}); err == nil {
+		t.Error("unknown color mode expect error")
+	}
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//Compressor compresses the rotated files of the file handler
+type Compressor interface {
+	//Ext is the extension of the compressed file like .gz,
+	//empty if the files are kept as they are
+	Ext() string
+	//NewWriter returns a writer compressing to w at level,
+	//the meaning of level is up to the compressor
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+}
+
+var (
+	compressorLock sync.RWMutex
+	compressors    = make(map[string]Compressor)
+)
+
+func init() {
+	RegisterCompressor("gzip", gzipCompressor{})
+	RegisterCompressor("zlib", zlibCompressor{})
+	RegisterCompressor("flate", flateCompressor{})
+	RegisterCompressor("none", noneCompressor{})
+}
+
+//RegisterCompressor makes a compressor available to the file handler by name,
+//it panics if c is nil or the name is registered twice
+func RegisterCompressor(name string, c Compressor) {
+	compressorLock.Lock()
+	defer compressorLock.Unlock()
+	if c == nil {
+		panic("log: RegisterCompressor compressor is nil")
+	}
+	if _, dup := compressors[name]; dup {
+		panic("log: RegisterCompressor called twice for compressor " + name)
+	}
+	compressors[name] = c
+}
+
+//getCompressor returns the registered compressor
+func getCompressor(name string) (Compressor, error) {
+	compressorLock.RLock()
+	c, ok := compressors[name]
+	compressorLock.RUnlock()
+	if !ok {
+		return nil, errors.New("Unknown compressor")
+	}
+	return c, nil
+}
+
+//trimCompressedExt returns name without the extension of a registered
+//compressor, false if it has none
+func trimCompressedExt(name string) (string, bool) {
+	compressorLock.RLock()
+	defer compressorLock.RUnlock()
+	for _, c := range compressors {
+		if ext := c.Ext(); ext != "" && strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), true
+		}
+	}
+	return name, false
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Ext() string {
+	return ".gz"
+}
+
+func (gzipCompressor) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, level)
+}
+
+type zlibCompressor struct{}
+
+func (zlibCompressor) Ext() string {
+	return ".zz"
+}
+
+func (zlibCompressor) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, level)
+}
+
+type flateCompressor struct{}
+
+func (flateCompressor) Ext() string {
+	return ".deflate"
+}
+
+func (flateCompressor) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return flate.NewWriter(w, level)
+}
+
+//noneCompressor keeps the rotated files as they are
+type noneCompressor struct{}
+
+func (noneCompressor) Ext() string {
+	return ""
+}
+
+func (noneCompressor) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+//compressFile compresses path to path without .log plus the extension of c,
+//the archive is written to a temp name and renamed when it is complete,
+//so retention never sees a half written one, path is removed on success
+func compressFile(path string, c Compressor, level int) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	dst := strings.TrimSuffix(path, ".log") + c.Ext()
+	out, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := out.Name()
+	err = func() error {
+		defer out.Close()
+		w, err := c.NewWriter(out, level)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, in); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if err := out.Chmod(0644); err != nil {
+			return err
+		}
+		return out.Close()
+	}()
+	if err == nil {
+		//keep the time of the rotated file to order the backups
+		if info, e := in.Stat(); e == nil {
+			os.Chtimes(tmp, info.ModTime(), info.ModTime())
+		}
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
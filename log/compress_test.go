@@ -0,0 +1,164 @@
+package log
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//upperCompressor is a compressor for test which upper cases the file
+type upperCompressor struct{}
+
+func (upperCompressor) Ext() string {
+	return ".up"
+}
+
+func (upperCompressor) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return upperWriter{w}, nil
+}
+
+type upperWriter struct {
+	io.Writer
+}
+
+func (w upperWriter) Write(p []byte) (int, error) {
+	return w.Writer.Write([]byte(strings.ToUpper(string(p))))
+}
+
+func (upperWriter) Close() error {
+	return nil
+}
+
+func init() {
+	RegisterCompressor("upper", upperCompressor{})
+}
+
+func TestCompress(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"zlib": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		"flate": func(r io.Reader) (io.Reader, error) {
+			return flate.NewReader(r), nil
+		},
+		"none":  func(r io.Reader) (io.Reader, error) { return r, nil },
+		"upper": func(r io.Reader) (io.Reader, error) { return r, nil },
+	}
+	exts := map[string]string{"gzip": ".gz", "zlib": ".zz", "flate": ".deflate", "none": ".log", "upper": ".up"}
+	for name, reader := range readers {
+		dir := t.TempDir()
+		c := DefaultFileConfig()
+		c.Path = filepath.Join(dir, "app.log")
+		c.Compression = name
+		c.CompressLevel = gzip.BestSpeed
+		hd, err := NewFileHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := hd.(*fileHandler)
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "rotated"})
+		h.mu.Lock()
+		h.setRotateTime(time.Now().Add(-h.rotateInterval - time.Second))
+		h.mu.Unlock()
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "current"})
+		//waits for the compression
+		h.Close()
+
+		files, _ := filepath.Glob(filepath.Join(dir, "app.log-*"))
+		if len(files) != 1 || !strings.HasSuffix(files[0], exts[name]) {
+			t.Errorf("%s: backups are %v", name, files)
+			continue
+		}
+		f, err := os.Open(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := reader(f)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		b, err := io.ReadAll(r)
+		f.Close()
+		expect := "] rotated\n"
+		if name == "upper" {
+			expect = strings.ToUpper(expect)
+		}
+		if err != nil || !strings.HasSuffix(string(b), expect) {
+			t.Errorf("%s: backup content is %q, err %v", name, b, err)
+		}
+	}
+}
+
+func TestCompressConfig(t *testing.T) {
+	c := DefaultFileConfig()
+	c.Path = "test.log"
+	c.Compression = "lz4"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "compression") {
+		t.Error("unknown compression err is", err)
+	}
+	c.Compression = "gzip"
+	c.CompressLevel = 10
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "compressLevel") {
+		t.Error("bad level err is", err)
+	}
+	//not used if not compressed
+	c.IsCompress = false
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("register twice expect panic")
+		}
+	}()
+	RegisterCompressor("gzip", gzipCompressor{})
+}
+
+func TestIsBackup(t *testing.T) {
+	cases := map[string]bool{
+		"app.log-20200101.log":           true,
+		"app.log-20200101.gz":            true,
+		"app.log-20200101.zz":            true,
+		"app.log-20200101.deflate":       true,
+		"app.log-20200101-150405.log":    true,
+		"app.log-20200101-150405.2.gz":   true,
+		"app.log-2020010115.log":         true,
+		"app.log-202001011504.1.log":     true,
+		"app.log-2020-W01.gz":            true,
+		"app.log-20200101.gz.123456.tmp": false,
+		"app.log-20200101":               false,
+		"app.log.lock":                   false,
+		"app.log2-20200101.gz":           false,
+		"app.log-error.log":              false,
+		"app.log-20240101-audit.log":     false,
+		"app.log-20200101-150405.x.log":  false,
+	}
+	for name, expect := range cases {
+		if isBackup("app.log", name) != expect {
+			t.Errorf("isBackup %s expect %v", name, expect)
+		}
+	}
+}
+
+func TestCompressFileKeepsTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log-20200101-000000.log")
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	writeBackup(t, path, 100, 0)
+	os.Chtimes(path, mtime, mtime)
+	if err := compressFile(path, gzipCompressor{}, gzip.DefaultCompression); err != nil {
+		t.Fatal(err)
+	}
+	info, exist := FileExists(strings.TrimSuffix(path, ".log") + ".gz")
+	if !exist || !info.ModTime().Equal(mtime) {
+		t.Error("compressed backup time is not kept:", info)
+	}
+	if _, exist := FileExists(path); exist {
+		t.Error("rotated file is not removed")
+	}
+}
@@ -0,0 +1,663 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ConfigError reports an invalid value of a config key
+type ConfigError struct {
+	Key   string
+	Value interface{}
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("log config %s=%v: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+//DecodeConfig sets the fields of the struct pointed by dst from config,
+//fields are matched by the tag config:"key" or config:"key,kind",
+//kind size accepts strings like "100MB", kind level a level name like "warn"
+//and kind facility a syslog facility name,
+//numbers may be any int, uint or integral float type, json.Number or string,
+//durations may be a time.Duration, a string like "45m" or nanoseconds,
+//keys not in config keep the value of dst, a key of config matching
+//no field is an error
+func DecodeConfig(config map[string]interface{}, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("log: DecodeConfig dst must be a pointer to struct")
+	}
+	v = v.Elem()
+	t := v.Type()
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("config")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key, kind := tag, ""
+		if n := strings.IndexByte(tag, ','); n >= 0 {
+			key, kind = tag[:n], tag[n+1:]
+		}
+		known[key] = true
+		raw, ok := config[key]
+		if !ok {
+			continue
+		}
+		if err := setConfigValue(v.Field(i), kind, raw); err != nil {
+			return &ConfigError{Key: key, Value: raw, Err: err}
+		}
+	}
+	var unknown []string
+	for key := range config {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &ConfigError{Key: unknown[0], Value: config[unknown[0]], Err: errors.New("Unknown config key")}
+	}
+	return nil
+}
+
+//withoutKeys returns a copy of config without keys
+func withoutKeys(config map[string]interface{}, keys ...string) map[string]interface{} {
+	m := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		m[k] = v
+	}
+	for _, k := range keys {
+		delete(m, k)
+	}
+	return m
+}
+
+func setConfigValue(f reflect.Value, kind string, raw interface{}) error {
+	switch kind {
+	case "size":
+		n, err := toSize(raw)
+		if err != nil {
+			return err
+		}
+		return setConfigInt(f, n)
+	case "level":
+		n, err := toLevel(raw)
+		if err != nil {
+			return err
+		}
+		return setConfigInt(f, int64(n))
+	case "facility":
+		n, err := toFacility(raw)
+		if err != nil {
+			return err
+		}
+		return setConfigInt(f, int64(n))
+	}
+	if f.Type() == durationType {
+		d, err := toDuration(raw)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.Bool:
+		b, err := toBool(raw)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt64(raw)
+		if err != nil {
+			return err
+		}
+		return setConfigInt(f, n)
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("cannot use %T as string", raw)
+		}
+		f.SetString(s)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+func setConfigInt(f reflect.Value, n int64) error {
+	if f.OverflowInt(n) {
+		return fmt.Errorf("%d overflows %s", n, f.Type())
+	}
+	f.SetInt(n)
+	return nil
+}
+
+func toInt64(raw interface{}) (int64, error) {
+	switch v := raw.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return uintToInt64(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uintToInt64(v)
+	case float32:
+		return floatToInt64(float64(v))
+	case float64:
+		return floatToInt64(v)
+	case json.Number:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot use %T as integer", raw)
+	}
+}
+
+func uintToInt64(v uint64) (int64, error) {
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("%d overflows int64", v)
+	}
+	return int64(v), nil
+}
+
+func floatToInt64(v float64) (int64, error) {
+	if v != math.Trunc(v) || v > math.MaxInt64 || v < math.MinInt64 {
+		return 0, fmt.Errorf("%v is not an integer", v)
+	}
+	return int64(v), nil
+}
+
+func toBool(raw interface{}) (bool, error) {
+	switch v := raw.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	default:
+		return false, fmt.Errorf("cannot use %T as bool", raw)
+	}
+}
+
+func toDuration(raw interface{}) (time.Duration, error) {
+	switch v := raw.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(strings.TrimSpace(v))
+	default:
+		n, err := toInt64(raw)
+		return time.Duration(n), err
+	}
+}
+
+//toLevel accepts a level number or name like "trace" or "WARN"
+func toLevel(raw interface{}) (int, error) {
+	if s, ok := raw.(string); ok {
+		return ParseLevel(s)
+	}
+	n, err := toInt64(raw)
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+//toSize accepts a number of bytes or a string like 512KB, 100MB, 1GB
+func toSize(raw interface{}) (int64, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return toInt64(raw)
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TB", TB}, {"GB", GB}, {"MB", MB}, {"KB", KB}, {"T", TB},
+		{"G", GB}, {"M", MB}, {"K", KB}, {"B", 1},
+	}
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			num := strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil || f < 0 {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+			return int64(f * float64(unit.size)), nil
+		}
+	}
+	return toInt64(s)
+}
+
+//ConsoleConfig configures the console handler
+type ConsoleConfig struct {
+	Level      int    `config:"level,level"` //default TRACE, the logger level decides
+	Format     string `config:"format"`      //text or json, default text
+	Caller     bool   `config:"caller"`      //write file:line of the caller
+	Stack      bool   `config:"stack"`       //write stack traces, see WithStackLevel
+	Color      string `config:"color"`       //auto, always or never, default auto, text only
+	Stderr     bool   `config:"stderr"`      //write WARN and above to stderr
+	TimeFormat string `config:"timeFormat"`  //rfc3339, millis or a layout like 15:04:05
+	UTC        bool   `config:"utc"`
+	Align      bool   `config:"align"` //align the fields, text only
+}
+
+func DefaultConsoleConfig() ConsoleConfig {
+	return ConsoleConfig{Level: TraceLevel}
+}
+
+func (c *ConsoleConfig) Validate() error {
+	if _, err := newEncoder(c.Format, encodeOptions{}); err != nil {
+		return &ConfigError{Key: "format", Value: c.Format, Err: err}
+	}
+	if err := checkColorMode(c.Color); err != nil {
+		return &ConfigError{Key: "color", Value: c.Color, Err: err}
+	}
+	return nil
+}
+
+//FileConfig configures the rolling file handler, start from DefaultFileConfig
+type FileConfig struct {
+	Level          int           `config:"level,level"`    //default TRACE, the logger level decides
+	MaxLevel       int           `config:"maxLevel,level"` //messages above it are skipped, default PANIC
+	Format         string        `config:"format"`
+	Path           string        `config:"path"`
+	IsCompress     bool          `config:"isCompress"`     //default true
+	Compression    string        `config:"compression"`    //gzip, zlib, flate, none or registered by RegisterCompressor, default gzip
+	CompressLevel  int           `config:"compressLevel"`  //like gzip.BestSpeed, default gzip.DefaultCompression
+	IsRollingFile  bool          `config:"isRollingFile"`  //default true
+	MaxRollingTime time.Duration `config:"maxRollingTime"` //default 7days
+	MaxRollingNum  int           `config:"maxRollingNum"`  //default 7, mean one day one file
+	MaxFileSize    int64         `config:"maxFileSize,size"`
+	RotateSchedule string        `config:"rotateSchedule"`    //hourly, daily, weekly or cron like "0 */6 * * *"
+	MaxBackups     int           `config:"maxBackups"`        //rotated files to keep, 0 means no limit
+	MaxTotalSize   int64         `config:"maxTotalSize,size"` //bytes of rotated files to keep, 0 means no limit
+	DryRun         bool          `config:"dryRun"`            //log the files retention would delete
+	Shared         bool          `config:"shared"`            //several processes write the file, rotation is locked by path.lock, a backup is compressed on the next rotation
+	ExternalRotate bool          `config:"externalRotate"`    //never rotate, reopen the file moved by logrotate etc
+	CheckInterval  time.Duration `config:"checkInterval"`     //default 45m
+	Caller         bool          `config:"caller"`
+	Stack          bool          `config:"stack"`
+}
+
+func DefaultFileConfig() FileConfig {
+	return FileConfig{
+		Level:          TraceLevel,
+		MaxLevel:       PanicLevel,
+		IsCompress:     true,
+		Compression:    "gzip",
+		CompressLevel:  gzip.DefaultCompression,
+		IsRollingFile:  true,
+		MaxRollingTime: 7 * 24 * time.Hour,
+		MaxRollingNum:  7,
+		MaxFileSize:    100 * MB,
+		CheckInterval:  45 * time.Minute,
+	}
+}
+
+func (c *FileConfig) Validate() error {
+	if _, err := newEncoder(c.Format, encodeOptions{}); err != nil {
+		return &ConfigError{Key: "format", Value: c.Format, Err: err}
+	}
+	if c.MaxLevel < c.Level {
+		return &ConfigError{Key: "maxLevel", Value: c.MaxLevel, Err: errors.New("must not be below level")}
+	}
+	if c.Path == "" {
+		return &ConfigError{Key: "path", Value: c.Path, Err: errors.New("Logger must config log path")}
+	}
+	if c.MaxRollingTime <= 0 {
+		return &ConfigError{Key: "maxRollingTime", Value: c.MaxRollingTime, Err: errors.New("must be positive")}
+	}
+	if c.MaxRollingNum <= 0 {
+		return &ConfigError{Key: "maxRollingNum", Value: c.MaxRollingNum, Err: errors.New("must be positive")}
+	}
+	if c.MaxFileSize <= 0 {
+		return &ConfigError{Key: "maxFileSize", Value: c.MaxFileSize, Err: errors.New("must be positive")}
+	}
+	if c.RotateSchedule != "" {
+		if _, err := parseSchedule(c.RotateSchedule); err != nil {
+			return &ConfigError{Key: "rotateSchedule", Value: c.RotateSchedule, Err: err}
+		}
+	}
+	if c.MaxBackups < 0 {
+		return &ConfigError{Key: "maxBackups", Value: c.MaxBackups, Err: errors.New("must not be negative")}
+	}
+	if c.MaxTotalSize < 0 {
+		return &ConfigError{Key: "maxTotalSize", Value: c.MaxTotalSize, Err: errors.New("must not be negative")}
+	}
+	if c.CheckInterval <= 0 {
+		return &ConfigError{Key: "checkInterval", Value: c.CheckInterval, Err: errors.New("must be positive")}
+	}
+	if c.IsCompress {
+		cp, err := getCompressor(c.Compression)
+		if err != nil {
+			return &ConfigError{Key: "compression", Value: c.Compression, Err: err}
+		}
+		w, err := cp.NewWriter(io.Discard, c.CompressLevel)
+		if err != nil {
+			return &ConfigError{Key: "compressLevel", Value: c.CompressLevel, Err: err}
+		}
+		w.Close()
+	}
+	return nil
+}
+
+//SyslogConfig configures the syslog handler
+type SyslogConfig struct {
+	Level    int    `config:"level,level"` //default TRACE, the logger level decides
+	Network  string `config:"network"`     //udp, tcp, unix, unixgram or empty for the local socket, tcp4 and the like are also allowed
+	Addr     string `config:"addr"`
+	RFC      int    `config:"rfc"`               //5424 or 3164, default 5424
+	Facility int    `config:"facility,facility"` //number or name like local0, default user
+	AppName  string `config:"appName"`           //default the program name
+	Hostname string `config:"hostname"`          //default os.Hostname
+	//messages are dropped while waiting to dial again after a failure
+	MinBackoff time.Duration `config:"minBackoff"` //default 1s
+	MaxBackoff time.Duration `config:"maxBackoff"` //default 1m
+}
+
+func DefaultSyslogConfig() SyslogConfig {
+	return SyslogConfig{
+		Level:      TraceLevel,
+		RFC:        5424,
+		Facility:   syslogFacilities["user"],
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+	}
+}
+
+func (c *SyslogConfig) Validate() error {
+	switch c.Network {
+	case "", "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return &ConfigError{Key: "network", Value: c.Network, Err: errors.New("Unknown syslog network")}
+	}
+	if c.Network != "" && c.Addr == "" {
+		return &ConfigError{Key: "addr", Value: c.Addr, Err: errors.New("syslog must config addr")}
+	}
+	if c.RFC != 5424 && c.RFC != 3164 {
+		return &ConfigError{Key: "rfc", Value: c.RFC, Err: errors.New("must be 5424 or 3164")}
+	}
+	if c.MinBackoff <= 0 {
+		return &ConfigError{Key: "minBackoff", Value: c.MinBackoff, Err: errors.New("must be positive")}
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		return &ConfigError{Key: "maxBackoff", Value: c.MaxBackoff, Err: errors.New("must not be less than minBackoff")}
+	}
+	if c.Facility < 0 || c.Facility > 23 {
+		return &ConfigError{Key: "facility", Value: c.Facility, Err: errors.New("must be in [0, 23]")}
+	}
+	return nil
+}
+
+//NetConfig configures the net handler
+type NetConfig struct {
+	Level         int           `config:"level,level"` //default TRACE, the logger level decides
+	Format        string        `config:"format"`
+	Network       string        `config:"network"` //default tcp
+	Addr          string        `config:"addr"`
+	BatchSize     int           `config:"batchSize"`     //default 100 lines
+	FlushInterval time.Duration `config:"flushInterval"` //default 1s
+	MinBackoff    time.Duration `config:"minBackoff"`    //default 1s
+	MaxBackoff    time.Duration `config:"maxBackoff"`    //default 1m
+	SpoolPath     string        `config:"spoolPath"`
+	MaxSpoolSize  int64         `config:"maxSpoolSize,size"` //default 100MB
+	IsCompress    bool          `config:"isCompress"`        //default true
+	Caller        bool          `config:"caller"`
+	Stack         bool          `config:"stack"` //a stack breaks the one line per message framing
+}
+
+func DefaultNetConfig() NetConfig {
+	return NetConfig{
+		Level:         TraceLevel,
+		Network:       "tcp",
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		MinBackoff:    time.Second,
+		MaxBackoff:    time.Minute,
+		MaxSpoolSize:  100 * MB,
+		IsCompress:    true,
+	}
+}
+
+func (c *NetConfig) Validate() error {
+	if _, err := newEncoder(c.Format, encodeOptions{}); err != nil {
+		return &ConfigError{Key: "format", Value: c.Format, Err: err}
+	}
+	if c.Addr == "" {
+		return &ConfigError{Key: "addr", Value: c.Addr, Err: errors.New("net logger must config addr")}
+	}
+	if c.SpoolPath == "" {
+		return &ConfigError{Key: "spoolPath", Value: c.SpoolPath, Err: errors.New("net logger must config spool path")}
+	}
+	if c.BatchSize <= 0 {
+		return &ConfigError{Key: "batchSize", Value: c.BatchSize, Err: errors.New("must be positive")}
+	}
+	if c.FlushInterval <= 0 {
+		return &ConfigError{Key: "flushInterval", Value: c.FlushInterval, Err: errors.New("must be positive")}
+	}
+	if c.MinBackoff <= 0 {
+		return &ConfigError{Key: "minBackoff", Value: c.MinBackoff, Err: errors.New("must be positive")}
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		return &ConfigError{Key: "maxBackoff", Value: c.MaxBackoff, Err: errors.New("must not be less than minBackoff")}
+	}
+	if c.MaxSpoolSize <= 0 {
+		return &ConfigError{Key: "maxSpoolSize", Value: c.MaxSpoolSize, Err: errors.New("must be positive")}
+	}
+	return nil
+}
+
+//MemoryConfig configures the memory handler
+type MemoryConfig struct {
+	Level        int           `config:"level,level"` //default TRACE, keep everything
+	Size         int           `config:"size"`        //messages to keep, default 5000
+	Format       string        `config:"format"`      //of the dumps
+	DumpPath     string        `config:"dumpPath"`    //dump to dumpPath-20060102-150405.log on dumpLevel, empty means never
+	DumpLevel    int           `config:"dumpLevel,level"`
+	DumpInterval time.Duration `config:"dumpInterval"` //at most one dump in it, except FATAL and PANIC, default 1m
+	Caller       bool          `config:"caller"`
+	Stack        bool          `config:"stack"`
+}
+
+func DefaultMemoryConfig() MemoryConfig {
+	return MemoryConfig{
+		Level:        TraceLevel,
+		Size:         5000,
+		DumpLevel:    ErrorLevel,
+		DumpInterval: time.Minute,
+	}
+}
+
+func (c *MemoryConfig) Validate() error {
+	if _, err := newEncoder(c.Format, encodeOptions{}); err != nil {
+		return &ConfigError{Key: "format", Value: c.Format, Err: err}
+	}
+	if c.Size <= 0 {
+		return &ConfigError{Key: "size", Value: c.Size, Err: errors.New("must be positive")}
+	}
+	if c.DumpInterval < 0 {
+		return &ConfigError{Key: "dumpInterval", Value: c.DumpInterval, Err: errors.New("must not be negative")}
+	}
+	return nil
+}
+
+//Config describes a logger and its handlers, see LoadConfig
+type Config struct {
+	Level              int           `config:"level,level"`
+	Modules            string        `config:"modules"` //module levels like dns=debug,upload=warn
+	BufferSize         int           `config:"bufferSize"`
+	Overflow           string        `config:"overflow"` //block, drop-newest, drop-oldest or sync
+	DropReportInterval time.Duration `config:"dropReportInterval"`
+	StackLevel         int           `config:"stackLevel,level"` //capture stacks from this level, default ERROR
+	//Sampling maps a level to its rule, see WithSampling
+	Sampling map[int]SamplingRule
+	//Handlers are passed to SetLogger, "type" is the registered handler type,
+	//"sampled": true switches on sampling for the handler
+	Handlers []map[string]interface{}
+}
+
+//LoadConfig reads a JSON config file like below, the logger level drops
+//messages before any handler, so it is trace to keep everything in the
+//memory handler and the other handlers filter by their own levels
+//
+//	{
+//	    "level": "trace",
+//	    "modules": "dns=debug,upload=warn",
+//	    "overflow": "drop-newest",
+//	    "sampling": {"error": {"first": 100, "thereafter": 100}},
+//	    "handlers": [
+//	        {"type": "console", "level": "info", "sampled": true},
+//	        {"type": "file", "path": "app.log", "level": "info", "maxFileSize": "100MB", "checkInterval": "45m"},
+//	        {"type": "file", "name": "error", "path": "error.log", "level": "error"},
+//	        {"type": "memory", "size": 5000, "dumpPath": "crash"}
+//	    ]
+//	}
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+//ParseConfig parses the JSON config of LoadConfig
+func ParseConfig(data []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	c := &Config{
+		BufferSize:         log_output_buffer,
+		Overflow:           OverflowBlock.String(),
+		DropReportInterval: drop_report_interval,
+		StackLevel:         ErrorLevel,
+	}
+	//sampling and handlers are decoded below
+	if err := DecodeConfig(withoutKeys(raw, "sampling", "handlers"), c); err != nil {
+		return nil, err
+	}
+	if c.BufferSize < 0 {
+		return nil, &ConfigError{Key: "bufferSize", Value: c.BufferSize, Err: errors.New("must not be negative")}
+	}
+	if _, err := ParseOverflowPolicy(c.Overflow); err != nil {
+		return nil, &ConfigError{Key: "overflow", Value: c.Overflow, Err: err}
+	}
+	if _, err := ParseModuleLevels(c.Modules); err != nil {
+		return nil, &ConfigError{Key: "modules", Value: c.Modules, Err: err}
+	}
+	if sampling, ok := raw["sampling"]; ok {
+		m, ok := sampling.(map[string]interface{})
+		if !ok {
+			return nil, &ConfigError{Key: "sampling", Value: sampling, Err: errors.New("must be an object")}
+		}
+		c.Sampling = make(map[int]SamplingRule)
+		for name, v := range m {
+			key := "sampling." + name
+			level, err := ParseLevel(name)
+			if err != nil {
+				return nil, &ConfigError{Key: key, Value: v, Err: err}
+			}
+			config, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, &ConfigError{Key: key, Value: v, Err: errors.New("must be an object")}
+			}
+			var rule SamplingRule
+			if err := DecodeConfig(config, &rule); err != nil {
+				return nil, &ConfigError{Key: key, Value: v, Err: err}
+			}
+			if err := rule.Validate(); err != nil {
+				return nil, &ConfigError{Key: key, Value: v, Err: err}
+			}
+			c.Sampling[level] = rule
+		}
+	}
+	if handlers, ok := raw["handlers"]; ok {
+		list, ok := handlers.([]interface{})
+		if !ok {
+			return nil, &ConfigError{Key: "handlers", Value: handlers, Err: errors.New("must be a list")}
+		}
+		for i, h := range list {
+			m, ok := h.(map[string]interface{})
+			if !ok {
+				return nil, &ConfigError{Key: fmt.Sprintf("handlers[%d]", i), Value: h, Err: errors.New("must be an object")}
+			}
+			if _, ok := m["type"].(string); !ok {
+				return nil, &ConfigError{Key: fmt.Sprintf("handlers[%d].type", i), Value: m["type"], Err: errors.New("must be a handler type")}
+			}
+			c.Handlers = append(c.Handlers, m)
+		}
+	}
+	return c, nil
+}
+
+//NewLogger returns a logger set up by c
+func (c *Config) NewLogger() (Logger, error) {
+	policy, err := ParseOverflowPolicy(c.Overflow)
+	if err != nil {
+		return nil, &ConfigError{Key: "overflow", Value: c.Overflow, Err: err}
+	}
+	modules, err := ParseModuleLevels(c.Modules)
+	if err != nil {
+		return nil, &ConfigError{Key: "modules", Value: c.Modules, Err: err}
+	}
+	opts := []Option{
+		WithBufferSize(c.BufferSize),
+		WithOverflowPolicy(policy),
+		WithDropReportInterval(c.DropReportInterval),
+		WithStackLevel(c.StackLevel),
+	}
+	for level, rule := range c.Sampling {
+		opts = append(opts, WithSampling(level, rule))
+	}
+	logger := NewLogger(opts...)
+	logger.SetLevel(c.Level)
+	logger.(*LoggerImp).setModules(modules)
+	for i, config := range c.Handlers {
+		handlerType, _ := config["type"].(string)
+		if err := logger.SetLogger(handlerType, withoutKeys(config, "type")); err != nil {
+			logger.Close(context.Background())
+			return nil, fmt.Errorf("log config handlers[%d]: %w", i, err)
+		}
+	}
+	return logger, nil
+}
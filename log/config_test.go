@@ -0,0 +1,207 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	c := DefaultFileConfig()
+	config := map[string]interface{}{
+		"path":           "a.log",
+		"maxFileSize":    100, //int for int64 must not panic
+		"maxRollingNum":  float64(3),
+		"checkInterval":  "45s",
+		"maxRollingTime": time.Hour,
+		"isCompress":     "false",
+	}
+	if err := DecodeConfig(config, &c); err != nil {
+		t.Fatal("decode config error:", err)
+	}
+	if c.Path != "a.log" || c.MaxFileSize != 100 || c.MaxRollingNum != 3 ||
+		c.CheckInterval != 45*time.Second || c.MaxRollingTime != time.Hour ||
+		c.IsCompress || !c.IsRollingFile {
+		t.Errorf("decode config error: %+v", c)
+	}
+
+	levels := map[interface{}]int{"trace": TraceLevel, "WARN": WarnLevel, 3: ErrorLevel, "4": FatalLevel}
+	for raw, level := range levels {
+		c := DefaultConsoleConfig()
+		if err := DecodeConfig(map[string]interface{}{"level": raw}, &c); err != nil || c.Level != level {
+			t.Errorf("level %v expect %d but is %d, %v", raw, level, c.Level, err)
+		}
+	}
+	if err := DecodeConfig(map[string]interface{}{"level": "loud"}, new(ConsoleConfig)); err == nil {
+		t.Error("unknown level name expect error")
+	}
+
+	sizes := map[string]int64{"512": 512, "2KB": 2 * KB, "100MB": 100 * MB, "1.5g": GB + GB/2}
+	for s, size := range sizes {
+		c := DefaultFileConfig()
+		if err := DecodeConfig(map[string]interface{}{"maxFileSize": s}, &c); err != nil || c.MaxFileSize != size {
+			t.Errorf("size %q expect %d but is %d, %v", s, size, c.MaxFileSize, err)
+		}
+	}
+
+	for _, config := range []map[string]interface{}{
+		{"maxFileSize": "lots"},
+		{"maxRollingNum": 1.5},
+		{"maxRollingNum": "seven"},
+		{"checkInterval": "soon"},
+		{"isCompress": 1},
+		{"path": 1},
+	} {
+		c := DefaultFileConfig()
+		var cerr *ConfigError
+		if err := DecodeConfig(config, &c); !errors.As(err, &cerr) {
+			t.Errorf("decode %v expect ConfigError but is %v", config, err)
+		}
+	}
+	if err := DecodeConfig(config, c); err == nil {
+		t.Error("decode to a non pointer expect error")
+	}
+	var cerr *ConfigError
+	err := DecodeConfig(map[string]interface{}{"path": "a.log", "maxFileSzie": "1MB"}, &c)
+	if !errors.As(err, &cerr) || cerr.Key != "maxFileSzie" {
+		t.Error("unknown key expect ConfigError naming it but is", err)
+	}
+}
+
+func TestSetLoggerName(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close(context.Background())
+	path := filepath.Join(t.TempDir(), "a.log")
+	var cerr *ConfigError
+	err := logger.SetLogger("file", map[string]interface{}{"path": path, "name": 1})
+	if !errors.As(err, &cerr) || cerr.Key != "name" {
+		t.Error("name expect ConfigError but is", err)
+	}
+	if _, exist := FileExists(path); exist {
+		t.Error("handler is set up with an invalid name")
+	}
+	if err := logger.SetLogger("file", map[string]interface{}{"path": path, "name": "a", "sampled": true}); err != nil {
+		t.Error("set logger error:", err)
+	}
+}
+
+func TestDefaultConfigLevel(t *testing.T) {
+	levels := map[string]int{
+		"console": DefaultConsoleConfig().Level,
+		"file":    DefaultFileConfig().Level,
+		"syslog":  DefaultSyslogConfig().Level,
+		"net":     DefaultNetConfig().Level,
+		"memory":  DefaultMemoryConfig().Level,
+	}
+	for name, level := range levels {
+		if level != TraceLevel {
+			t.Errorf("default level of %s expect TRACE but is %d", name, level)
+		}
+	}
+
+	//trace messages pass a handler without level
+	logger := NewLogger()
+	logger.SetLevel(TraceLevel)
+	path := filepath.Join(t.TempDir(), "trace.log")
+	if err := logger.SetLogger("file", map[string]interface{}{"path": path}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	logger.Trace("deep")
+	logger.Close(context.Background())
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), "deep") {
+		t.Errorf("trace message is dropped: %q %v", data, err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close(context.Background())
+	for _, config := range []map[string]interface{}{
+		{},
+		{"path": "a.log", "maxRollingNum": 0},
+		{"path": "a.log", "format": "xml"},
+		{"path": "a.log", "maxFileSize": -1},
+	} {
+		var cerr *ConfigError
+		if err := logger.SetLogger("file", config); !errors.As(err, &cerr) {
+			t.Errorf("set logger %v expect ConfigError but is %v", config, err)
+		}
+	}
+	if _, err := NewFileHandler(FileConfig{Path: "a.log"}); err == nil {
+		t.Error("file config without rolling settings expect error")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	all := filepath.Join(dir, "all.log")
+	errlog := filepath.Join(dir, "error.log")
+	path := filepath.Join(dir, "log.json")
+	data := `{
+		"level": 1,
+		"bufferSize": 16,
+		"overflow": "drop-newest",
+		"handlers": [
+			{"type": "file", "path": "` + all + `", "maxFileSize": "100MB", "checkInterval": "45m"},
+			{"type": "file", "name": "error", "path": "` + errlog + `", "level": 3, "format": "json"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal("load config error:", err)
+	}
+	if c.Level != InfoLevel || c.BufferSize != 16 || c.Overflow != "drop-newest" || len(c.Handlers) != 2 {
+		t.Fatalf("load config error: %+v", c)
+	}
+	logger, err := c.NewLogger()
+	if err != nil {
+		t.Fatal("new logger error:", err)
+	}
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Error("error")
+	logger.Close(context.Background())
+
+	fb, _ := os.ReadFile(all)
+	if s := string(fb); strings.Contains(s, "debug") || !strings.Contains(s, "[INFO] info") ||
+		!strings.Contains(s, "[ERROR] error") {
+		t.Error("all.log content error:", s)
+	}
+	fb, _ = os.ReadFile(errlog)
+	if s := string(fb); strings.Contains(s, "info") || !strings.Contains(s, `"level":"ERROR"`) {
+		t.Error("error.log content error:", s)
+	}
+
+	for _, data := range []string{
+		`{"overflow": "spill"}`,
+		`{"level": "loud"}`,
+		`{"handlers": [{"path": "a.log"}]}`,
+		`{"handlers": {"type": "console"}}`,
+		`{"sampling": {"loud": {"first": 1}}}`,
+		`{"sampling": {"error": {"first": -1}}}`,
+		`{"sampling": {"error": 100}}`,
+		`not json`,
+	} {
+		if _, err := ParseConfig([]byte(data)); err == nil {
+			t.Error("parse config expect error:", data)
+		}
+	}
+	c, err = ParseConfig([]byte(`{"sampling": {"warn": {"first": 10, "thereafter": "100"}}}`))
+	if err != nil || c.Sampling[WarnLevel] != (SamplingRule{First: 10, Thereafter: 100}) {
+		t.Errorf("parse sampling error: %+v %v", c, err)
+	}
+	c, err = ParseConfig([]byte(`{"handlers": [{"type": "file", "path": "` + all + `", "maxRollingNum": 0}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NewLogger(); err == nil {
+		t.Error("invalid handler config expect error")
+	}
+}
@@ -0,0 +1,80 @@
+package log
+
+import "context"
+
+//the keys of the fields set by the helpers below
+const (
+	RequestIDKey = "request_id"
+	UserIDKey    = "user_id"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+type fieldsKey struct{}
+
+//ContextWithFields returns a copy of ctx carrying the key/value pairs,
+//which are attached to the messages logged by InfoContext etc with it
+//and by the slog handler, a key already in ctx is replaced
+func ContextWithFields(ctx context.Context, kv ...interface{}) context.Context {
+	add := argsToFields(kv)
+	old := FieldsFromContext(ctx)
+	fields := make([]Field, 0, len(old)+len(add))
+	for _, f := range old {
+		replaced := false
+		for _, a := range add {
+			if a.Key == f.Key {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fields = append(fields, f)
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, append(fields, add...))
+}
+
+//FieldsFromContext returns the fields ctx carries, they must not be modified
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
+
+//fieldFromContext returns the string value of key in ctx
+func fieldFromContext(ctx context.Context, key string) string {
+	for _, f := range FieldsFromContext(ctx) {
+		if f.Key == key {
+			s, _ := f.Value.(string)
+			return s
+		}
+	}
+	return ""
+}
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return ContextWithFields(ctx, RequestIDKey, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	return fieldFromContext(ctx, RequestIDKey)
+}
+
+func ContextWithUserID(ctx context.Context, id string) context.Context {
+	return ContextWithFields(ctx, UserIDKey, id)
+}
+
+func UserIDFromContext(ctx context.Context) string {
+	return fieldFromContext(ctx, UserIDKey)
+}
+
+//ContextWithTrace sets the trace and span IDs, like those of a W3C traceparent
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return ContextWithFields(ctx, TraceIDKey, traceID, SpanIDKey, spanID)
+}
+
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	return fieldFromContext(ctx, TraceIDKey), fieldFromContext(ctx, SpanIDKey)
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+//fieldMap returns the fields of lm by key
+func fieldMap(lm *LogMesg) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, f := range lm.Fields {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+func TestContextFields(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	ctx := ContextWithRequestID(context.Background(), "r1")
+	ctx = ContextWithUserID(ctx, "u1")
+	ctx = ContextWithTrace(ctx, "t1", "s1")
+	ctx = ContextWithFields(ctx, "tenant", "a", RequestIDKey, "r2")
+	if id := RequestIDFromContext(ctx); id != "r2" {
+		t.Error("request id is", id)
+	}
+	if traceID, spanID := TraceFromContext(ctx); traceID != "t1" || spanID != "s1" {
+		t.Error("trace is", traceID, spanID)
+	}
+	if n := len(FieldsFromContext(ctx)); n != 5 {
+		t.Error("fields expect 5 but is", n)
+	}
+
+	logger.With("app", "x").InfoContext(ctx, "hi %s", "bob", "n", 1)
+	logger.Info("no context")
+	logger.Flush()
+	lm := h.mesgs[0]
+	m := fieldMap(lm)
+	if lm.Mesg != "hi bob" || m["app"] != "x" || m[RequestIDKey] != "r2" || m[UserIDKey] != "u1" ||
+		m[TraceIDKey] != "t1" || m["tenant"] != "a" || m["n"] != 1 {
+		t.Errorf("message is %s %v", lm.Mesg, m)
+	}
+	if lm.Fields[0].Key != "app" || lm.Fields[len(lm.Fields)-1].Key != "n" {
+		t.Error("fields order is", lm.Fields)
+	}
+	if lm.Caller().Function != "github.com/Hacky-DH/goLib/log.TestContextFields" {
+		t.Error("caller is", lm.Caller().Function)
+	}
+	if len(h.mesgs[1].Fields) != 0 {
+		t.Error("fields without context:", h.mesgs[1].Fields)
+	}
+
+	//slog handler reads the fields of ctx
+	sh, err := NewSlogHandler(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(sh).InfoContext(ctx, "from slog")
+	logger.Flush()
+	if m := fieldMap(h.last()); m[RequestIDKey] != "r2" {
+		t.Error("slog fields are", m)
+	}
+	//so slog logger of it does not pass them twice
+	NewSlogLogger(sh).InfoContext(ctx, "from slog logger")
+	logger.Flush()
+	if n := len(h.last().Fields); n != 5 {
+		t.Error("slog logger fields expect 5 but is", h.last().Fields)
+	}
+}
+
+func TestSlogLoggerContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewJSONHandler(&buf, nil))
+	logger.WarnContext(ContextWithRequestID(context.Background(), "r1"), "slow", "ms", 120)
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[RequestIDKey] != "r1" || m["ms"] != 120.0 || m["level"] != "WARN" {
+		t.Error("slog logger output is", buf.String())
+	}
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	text_time_format = "2006/01/02 15:04:05"
+	json_time_format = "2006-01-02T15:04:05.000Z07:00"
+	text_align_width = 40 //the fields of text start at least at this column of the message
+)
+
+//encoder renders a message to one line without the trailing newline,
+//except the stack trace which takes the following lines in text
+type encoder interface {
+	encode(buf *bytes.Buffer, lm *LogMesg)
+}
+
+//encodeOptions are the optional parts of a message to write
+type encodeOptions struct {
+	caller     bool   //file:line of the caller
+	stack      bool   //the stack trace if any
+	timeLayout string //default text_time_format or json_time_format
+	utc        bool
+	color      bool //ANSI colored level and keys, text only
+	align      bool //align the fields, text only
+}
+
+func (o *encodeOptions) formatTime(t time.Time, layout string) string {
+	if o.timeLayout != "" {
+		layout = o.timeLayout
+	}
+	if o.utc {
+		t = t.UTC()
+	}
+	return t.Format(layout)
+}
+
+//timeLayout returns the layout of the time format name rfc3339 or millis,
+//other names are taken as a layout of package time
+func timeLayout(format string) string {
+	switch format {
+	case "rfc3339":
+		return "2006-01-02T15:04:05Z07:00"
+	case "millis":
+		return "2006-01-02 15:04:05.000"
+	default:
+		return format
+	}
+}
+
+//newEncoder returns the encoder of format "text" or "json",
+//default is text
+func newEncoder(format string, opts encodeOptions) (encoder, error) {
+	switch format {
+	case "", "text":
+		return &textEncoder{opts}, nil
+	case "json":
+		return &jsonEncoder{opts}, nil
+	default:
+		return nil, errors.New("Unknown log format " + format)
+	}
+}
+
+//textEncoder writes lines like
+//2006/01/02 15:04:05 [INFO] [name] dir/file.go:12 mesg key=value
+//the stack trace follows in the next lines
+type textEncoder struct {
+	encodeOptions
+}
+
+func (e *textEncoder) encode(buf *bytes.Buffer, lm *LogMesg) {
+	buf.WriteString(e.formatTime(lm.Time, text_time_format))
+	buf.WriteByte(' ')
+	if e.color {
+		buf.WriteString(levelColor(lm.Level))
+	}
+	buf.WriteByte('[')
+	buf.WriteString(LevelName(lm.Level))
+	buf.WriteByte(']')
+	if e.color {
+		buf.WriteString(color_reset)
+	}
+	buf.WriteByte(' ')
+	if lm.Name != "" {
+		buf.WriteByte('[')
+		buf.WriteString(lm.Name)
+		buf.WriteString("] ")
+	}
+	if e.caller && lm.PC != 0 {
+		frame := lm.Caller()
+		buf.WriteString(shortFile(frame.File))
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(frame.Line))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(lm.Mesg)
+	if e.align && len(lm.Fields) > 0 {
+		if n := utf8.RuneCountInString(lm.Mesg); n < text_align_width {
+			buf.WriteString(strings.Repeat(" ", text_align_width-n))
+		}
+	}
+	for _, field := range lm.Fields {
+		buf.WriteByte(' ')
+		if e.color {
+			buf.WriteString(color_cyan)
+			writeTextString(buf, field.Key)
+			buf.WriteString(color_reset)
+		} else {
+			writeTextString(buf, field.Key)
+		}
+		buf.WriteByte('=')
+		writeTextString(buf, textValue(field.Value))
+	}
+	if e.stack && lm.Stack != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(lm.Stack)
+	}
+}
+
+func textValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+//writeTextString quotes s if it is empty or contains spaces, quotes or '='
+func writeTextString(buf *bytes.Buffer, s string) {
+	needQuote := s == ""
+	for _, r := range s {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			needQuote = true
+			break
+		}
+	}
+	if needQuote {
+		buf.WriteString(strconv.Quote(s))
+	} else {
+		buf.WriteString(s)
+	}
+}
+
+//jsonEncoder writes one object per line like
+//{"time":"2006-01-02T15:04:05.000+08:00","level":"INFO","logger":"name",
+//"caller":"dir/file.go:12","func":"pkg.Func","msg":"mesg","key":"value","stack":"..."}
+type jsonEncoder struct {
+	encodeOptions
+}
+
+func (e *jsonEncoder) encode(buf *bytes.Buffer, lm *LogMesg) {
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, e.formatTime(lm.Time, json_time_format))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, LevelName(lm.Level))
+	if lm.Name != "" {
+		buf.WriteString(`,"logger":`)
+		writeJSONValue(buf, lm.Name)
+	}
+	if e.caller && lm.PC != 0 {
+		frame := lm.Caller()
+		buf.WriteString(`,"caller":`)
+		writeJSONValue(buf, shortFile(frame.File)+":"+strconv.Itoa(frame.Line))
+		buf.WriteString(`,"func":`)
+		writeJSONValue(buf, frame.Function)
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, lm.Mesg)
+	for _, field := range lm.Fields {
+		buf.WriteByte(',')
+		writeJSONValue(buf, field.Key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, field.Value)
+	}
+	if e.stack && lm.Stack != "" {
+		buf.WriteString(`,"stack":`)
+		writeJSONValue(buf, lm.Stack)
+	}
+	buf.WriteByte('}')
+}
+
+//writeJSONValue falls back to the string form of v if it can not be marshaled
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		if _, ok := v.(json.Marshaler); !ok {
+			v = err.Error()
+		}
+	}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		enc.Encode(fmt.Sprint(v))
+	}
+	//trim the newline added by Encode
+	if b := buf.Bytes(); len(b) > 0 && b[len(b)-1] == '\n' {
+		buf.Truncate(buf.Len() - 1)
+	}
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testMesg() *LogMesg {
+	return &LogMesg{
+		Level: WarnLevel,
+		Time:  time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC),
+		Mesg:  "disk <full>",
+		Fields: []Field{
+			{"path", "/data/a b"},
+			{"used", 0.95},
+			{"err", errors.New("no space")},
+		},
+	}
+}
+
+func TestTextEncoder(t *testing.T) {
+	enc, err := newEncoder("text", encodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc.encode(&buf, testMesg())
+	expect := `2020/01/02 03:04:05 [WARN] disk <full> path="/data/a b" used=0.95 err="no space"`
+	if buf.String() != expect {
+		t.Errorf("text encoder expect\n%s\nbut is\n%s", expect, buf.String())
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	enc, err := newEncoder("json", encodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc.encode(&buf, testMesg())
+	expect := `{"time":"2020-01-02T03:04:05.006Z","level":"WARN","msg":"disk <full>","path":"/data/a b","used":0.95,"err":"no space"}`
+	if buf.String() != expect {
+		t.Errorf("json encoder expect\n%s\nbut is\n%s", expect, buf.String())
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Error("json encoder output is invalid:", err)
+	}
+
+	if _, err := newEncoder("xml", encodeOptions{}); err == nil {
+		t.Error("unknown format expect error")
+	}
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"os"
+	"sync"
+)
+
+//osExit is replaced in tests
+var osExit = os.Exit
+
+var (
+	exitLock  sync.Mutex
+	exitHooks []func()
+)
+
+//RegisterExitHook adds a func run by Fatal before the process exits,
+//hooks run in the order they are registered
+func RegisterExitHook(hook func()) {
+	if hook == nil {
+		panic("log: RegisterExitHook hook is nil")
+	}
+	exitLock.Lock()
+	defer exitLock.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+//runExitHooks runs the hooks, a panic of a hook does not stop the others
+func runExitHooks() {
+	exitLock.Lock()
+	hooks := append([]func(){}, exitHooks...)
+	exitLock.Unlock()
+	for _, hook := range hooks {
+		func() {
+			defer func() { recover() }()
+			hook()
+		}()
+	}
+}
+
+//exit runs the exit hooks and exits with status 1
+func exit() {
+	runExitHooks()
+	osExit(1)
+}
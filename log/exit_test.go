@@ -0,0 +1,79 @@
+package log
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+//stubExit replaces os.Exit and the exit hooks until the test ends,
+//the returned slice records the exit codes
+func stubExit(t *testing.T) *[]int {
+	codes := new([]int)
+	exitLock.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitLock.Unlock()
+	osExit = func(code int) { *codes = append(*codes, code) }
+	t.Cleanup(func() {
+		exitLock.Lock()
+		exitHooks = hooks
+		exitLock.Unlock()
+		osExit = os.Exit
+	})
+	return codes
+}
+
+func TestFatal(t *testing.T) {
+	codes := stubExit(t)
+	//a dropping buffer must not drop the fatal message
+	logger := NewLogger(WithOverflowPolicy(OverflowDropNewest)).(*LoggerImp)
+	defer logger.Close(context.Background())
+	h := new(recordHandler)
+	logger.AddHandler("record", h)
+
+	var order []string
+	RegisterExitHook(func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if len(h.mesgs) == 1 && h.mesgs[0].Mesg == "bye 1" {
+			order = append(order, "flushed")
+		}
+		order = append(order, "first")
+	})
+	RegisterExitHook(func() { panic("hook failed") })
+	RegisterExitHook(func() { order = append(order, "last") })
+
+	logger.Fatal("bye %d", 1)
+	if len(order) != 3 || order[0] != "flushed" || order[2] != "last" {
+		t.Error("exit hooks run error:", order)
+	}
+	if len(*codes) != 1 || (*codes)[0] != 1 {
+		t.Error("exit codes expect [1] but is", *codes)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	defer func() {
+		if r := recover(); r != "oops 2" {
+			t.Errorf("panic value expect %q but is %v", "oops 2", r)
+		}
+		if lm := h.last(); lm.Level != PanicLevel || lm.Mesg != "oops 2" {
+			t.Errorf("panic message is not flushed: %+v", lm)
+		}
+	}()
+	logger.Panic("oops %d", 2, "k", "v")
+}
+
+func TestTrace(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	logger.Trace("hidden")
+	logger.SetLevel(TraceLevel)
+	logger.Trace("shown")
+	logger.Flush()
+	if len(h.mesgs) != 1 || h.last().Mesg != "shown" || LevelName(h.last().Level) != "TRACE" {
+		t.Error("trace messages error:", h.mesgs)
+	}
+	logger.Close(context.Background())
+}
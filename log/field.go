@@ -0,0 +1,130 @@
+package log
+
+import "fmt"
+
+const badKey = "!BADKEY"
+
+//Field is a typed key/value pair attached to a log message
+//the value is rendered by the handler, so it should not be modified after logging
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+//argsToFields converts alternating keys and values to fields,
+//a Field is taken as is, a key without value or a non string key
+//is stored under !BADKEY like log/slog does
+func argsToFields(args []interface{}) []Field {
+	fields := make([]Field, 0, (len(args)+1)/2)
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case Field:
+			fields = append(fields, arg)
+		case string:
+			if i+1 < len(args) {
+				fields = append(fields, Field{Key: arg, Value: args[i+1]})
+				i++
+			} else {
+				fields = append(fields, Field{Key: badKey, Value: arg})
+			}
+		default:
+			fields = append(fields, Field{Key: badKey, Value: arg})
+		}
+	}
+	return fields
+}
+
+//formatArgs formats the args consumed by format
+//and converts the rest to fields
+func formatArgs(format string, v []interface{}) (string, []Field) {
+	n := argsUsed(format)
+	if n > len(v) {
+		n = len(v)
+	}
+	mesg := fmt.Sprintf(format, v[:n]...)
+	if n == len(v) {
+		return mesg, nil
+	}
+	return mesg, argsToFields(v[n:])
+}
+
+//argsUsed returns the number of operands the verbs of format consume,
+//following the rules of fmt for '*' width/precision and explicit [n] indexes
+func argsUsed(format string) int {
+	used, argNum := 0, 0
+	end := len(format)
+	for i := 0; i < end; i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		//flags
+		for i < end && (format[i] == '+' || format[i] == '-' ||
+			format[i] == '#' || format[i] == ' ' || format[i] == '0') {
+			i++
+		}
+		//width
+		i, argNum = argIndex(format, i, argNum)
+		if i < end && format[i] == '*' {
+			i++
+			argNum++
+			if argNum > used {
+				used = argNum
+			}
+		} else {
+			for i < end && format[i] >= '0' && format[i] <= '9' {
+				i++
+			}
+		}
+		//precision
+		if i < end && format[i] == '.' {
+			i++
+			i, argNum = argIndex(format, i, argNum)
+			if i < end && format[i] == '*' {
+				i++
+				argNum++
+				if argNum > used {
+					used = argNum
+				}
+			} else {
+				for i < end && format[i] >= '0' && format[i] <= '9' {
+					i++
+				}
+			}
+		}
+		i, argNum = argIndex(format, i, argNum)
+		if i >= end {
+			break
+		}
+		if format[i] == '%' {
+			continue
+		}
+		argNum++
+		if argNum > used {
+			used = argNum
+		}
+	}
+	return used
+}
+
+//argIndex parses an explicit [n] argument index at format[i:]
+func argIndex(format string, i, argNum int) (int, int) {
+	if i >= len(format) || format[i] != '[' {
+		return i, argNum
+	}
+	n := 0
+	for j := i + 1; j < len(format); j++ {
+		c := format[j]
+		if c == ']' {
+			if n > 0 {
+				return j + 1, n - 1
+			}
+			return j + 1, argNum
+		}
+		if c < '0' || c > '9' {
+			return i, argNum
+		}
+		n = n*10 + int(c-'0')
+	}
+	return i, argNum
+}
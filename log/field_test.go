@@ -0,0 +1,38 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgsUsed(t *testing.T) {
+	cases := []struct {
+		format string
+		used   int
+	}{
+		{"plain", 0},
+		{"100%%", 0},
+		{"debug %d", 1},
+		{"%s %v %q", 3},
+		{"%-8s|%08.3f", 2},
+		{"%*d", 2},
+		{"%.*f", 2},
+		{"%[2]d %[1]d", 2},
+		{"%[3]*.[2]*[1]f", 3},
+		{"%d %%", 1},
+		{"trailing %", 0},
+	}
+	for _, c := range cases {
+		if used := argsUsed(c.format); used != c.used {
+			t.Errorf("argsUsed(%q) expect %d but is %d", c.format, c.used, used)
+		}
+	}
+}
+
+func TestArgsToFields(t *testing.T) {
+	fields := argsToFields([]interface{}{"k", 1, Field{"f", "v"}, 2, "dangling"})
+	expect := []Field{{"k", 1}, {"f", "v"}, {badKey, 2}, {badKey, "dangling"}}
+	if !reflect.DeepEqual(fields, expect) {
+		t.Errorf("argsToFields expect %v but is %v", expect, fields)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/Hacky-DH/goLib/log
 
-go 1.16
+go 1.21
@@ -0,0 +1,246 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//the range SIGUSR1/SIGUSR2 move the logger level in
+const (
+	lowestLevel  = TraceLevel
+	highestLevel = PanicLevel
+)
+
+//LevelName returns the name of level like INFO, LEVEL(n) if it is unknown
+func LevelName(level int) string {
+	switch level {
+	case TraceLevel:
+		return "TRACE"
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	case FatalLevel:
+		return "FATAL"
+	case PanicLevel:
+		return "PANIC"
+	default:
+		return "LEVEL(" + strconv.Itoa(level) + ")"
+	}
+}
+
+//ParseLevel parses a level name like "debug" or "WARN" or a level number
+func ParseLevel(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToUpper(s) {
+	case "TRACE":
+		return TraceLevel, nil
+	case "DEBUG":
+		return DebugLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "WARN", "WARNING":
+		return WarnLevel, nil
+	case "ERROR":
+		return ErrorLevel, nil
+	case "FATAL":
+		return FatalLevel, nil
+	case "PANIC":
+		return PanicLevel, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	return 0, errors.New("Unknown log level " + s)
+}
+
+//Leveler is implemented by handlers whose level can be changed at runtime
+type Leveler interface {
+	Level() int
+	SetLevel(level int)
+}
+
+//handlerLevel is embedded by handlers to implement Leveler
+type handlerLevel struct {
+	level atomic.Int32
+}
+
+func (h *handlerLevel) Level() int {
+	return int(h.level.Load())
+}
+
+func (h *handlerLevel) SetLevel(level int) {
+	h.level.Store(int32(level))
+}
+
+func (h *handlerLevel) enabled(level int) bool {
+	return int(h.level.Load()) <= level
+}
+
+//logLevelChange writes the change of a level bypassing the logger level
+func (l *LoggerImp) logLevelChange(target string, from, to interface{}) {
+	if level, ok := from.(int); ok {
+		from = LevelName(level)
+	}
+	if level, ok := to.(int); ok {
+		to = LevelName(level)
+	}
+	l.enqueue(&LogMesg{
+		Level: WarnLevel,
+		Time:  time.Now(),
+		Mesg:  "[log level] " + target + " level changed",
+		Fields: []Field{
+			{Key: "from", Value: from},
+			{Key: "to", Value: to},
+		},
+	})
+}
+
+//changeLevel sets the logger level and logs the change
+func (l *LoggerImp) changeLevel(level int) {
+	if from := int(l.level.Swap(int32(level))); from != level {
+		l.logLevelChange("logger", from, level)
+	}
+}
+
+//levelState is the body of the level http handler
+type levelState struct {
+	Level    *jsonLevel           `json:"level,omitempty"`
+	Modules  *string              `json:"modules,omitempty"`
+	Handlers map[string]jsonLevel `json:"handlers,omitempty"`
+}
+
+//jsonLevel is a level encoded as its name, a name or number is decoded
+type jsonLevel int
+
+func (l jsonLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(LevelName(int(l)))
+}
+
+func (l *jsonLevel) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var level int
+	var err error
+	switch v := v.(type) {
+	case string:
+		level, err = ParseLevel(v)
+	case float64:
+		level, err = int(v), nil
+		if float64(level) != v {
+			err = errors.New("log level must be an integer")
+		}
+	default:
+		err = errors.New("log level must be a name or number")
+	}
+	*l = jsonLevel(level)
+	return err
+}
+
+type levelHandler struct {
+	logger *LoggerImp
+}
+
+//NewLevelHandler returns a http.Handler to view and change the levels,
+//GET returns {"level":"INFO","modules":"dns=debug","handlers":{"console":"DEBUG"}},
+//PUT accepts the same body, levels may be names or numbers,
+//modules replaces all the module levels, keys not in the body are not changed,
+//logger must be created by NewLogger
+func NewLevelHandler(logger Logger) (http.Handler, error) {
+	l, ok := logger.(*LoggerImp)
+	if !ok {
+		return nil, ErrUnknownLogger
+	}
+	return &levelHandler{logger: l}, nil
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var state levelState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.apply(&state); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.state())
+}
+
+func (h *levelHandler) state() *levelState {
+	level := jsonLevel(h.logger.Level())
+	modules := h.logger.ModuleLevels()
+	state := &levelState{Level: &level, Modules: &modules, Handlers: make(map[string]jsonLevel)}
+	h.logger.hlock.RLock()
+	defer h.logger.hlock.RUnlock()
+	for _, output := range h.logger.outputs {
+		if leveler, ok := output.handler.(Leveler); ok {
+			state.Handlers[output.name] = jsonLevel(leveler.Level())
+		}
+	}
+	return state
+}
+
+//apply checks all the module levels and handler names before changing any level
+func (h *levelHandler) apply(state *levelState) error {
+	var modules map[string]int
+	if state.Modules != nil {
+		var err error
+		if modules, err = ParseModuleLevels(*state.Modules); err != nil {
+			return err
+		}
+	}
+	h.logger.hlock.RLock()
+	levelers := make(map[string]Leveler, len(state.Handlers))
+	for name := range state.Handlers {
+		for _, output := range h.logger.outputs {
+			if leveler, ok := output.handler.(Leveler); ok && output.name == name {
+				levelers[name] = leveler
+			}
+		}
+		if levelers[name] == nil {
+			h.logger.hlock.RUnlock()
+			return errors.New("Unknown log handler " + name)
+		}
+	}
+	h.logger.hlock.RUnlock()
+
+	if state.Level != nil {
+		h.logger.changeLevel(int(*state.Level))
+	}
+	if state.Modules != nil {
+		from := h.logger.ModuleLevels()
+		h.logger.setModules(modules)
+		if to := h.logger.ModuleLevels(); from != to {
+			h.logger.logLevelChange("module", from, to)
+		}
+	}
+	for name, level := range state.Handlers {
+		leveler := levelers[name]
+		if from := leveler.Level(); from != int(level) {
+			leveler.SetLevel(int(level))
+			h.logger.logLevelChange("handler "+name, from, int(level))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]int{
+		"debug": DebugLevel, "INFO": InfoLevel, "Warn": WarnLevel,
+		"warning": WarnLevel, " error ": ErrorLevel, "FATAL": FatalLevel, "2": WarnLevel,
+		"trace": TraceLevel, "Panic": PanicLevel, "-1": TraceLevel,
+	}
+	for s, level := range cases {
+		if l, err := ParseLevel(s); err != nil || l != level {
+			t.Errorf("ParseLevel(%q) expect %d but is %d, %v", s, level, l, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel unknown name expect error")
+	}
+}
+
+func serveLevel(h http.Handler, method, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+	return w
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := NewLogger().(*LoggerImp)
+	name := filepath.Join(t.TempDir(), "level.log")
+	if err := logger.SetLogger("file", map[string]interface{}{"path": name, "level": InfoLevel}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	h, err := NewLevelHandler(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLevelHandler(NewSlogLogger(slog.NewTextHandler(io.Discard, nil))); err != ErrUnknownLogger {
+		t.Error("slog logger expect ErrUnknownLogger but", err)
+	}
+
+	w := serveLevel(h, http.MethodGet, "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"level":"DEBUG","modules":"","handlers":{"file":"INFO"}}` {
+		t.Fatal("get level error:", w.Code, w.Body.String())
+	}
+
+	w = serveLevel(h, http.MethodPut, `{"level":"warn","modules":"dns=debug","handlers":{"file":2}}`)
+	var state map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &state)
+	if w.Code != http.StatusOK || state["level"] != "WARN" || state["modules"] != "dns=debug" ||
+		state["handlers"].(map[string]interface{})["file"] != "WARN" {
+		t.Fatal("put level error:", w.Code, w.Body.String())
+	}
+	if logger.Level() != WarnLevel {
+		t.Error("logger level expect WARN but is", LevelName(logger.Level()))
+	}
+
+	for _, body := range []string{`{"handlers":{"nope":"info"}}`, `{"level":"loud"}`, `{"modules":"dns"}`, `{"level":1.5}`, `[`} {
+		if w := serveLevel(h, http.MethodPut, body); w.Code != http.StatusBadRequest {
+			t.Error("put", body, "expect 400 but is", w.Code)
+		}
+	}
+	if logger.Level() != WarnLevel {
+		t.Error("invalid put must not change the level")
+	}
+	if w := serveLevel(h, http.MethodPost, ""); w.Code != http.StatusMethodNotAllowed {
+		t.Error("post expect 405 but is", w.Code)
+	}
+
+	logger.Close(context.Background())
+	fb, _ := os.ReadFile(name)
+	if s := string(fb); strings.Count(s, "level changed") != 3 ||
+		!strings.Contains(s, `[log level] module level changed from="" to="dns=debug"`) ||
+		!strings.Contains(s, "[log level] logger level changed from=DEBUG to=WARN") ||
+		!strings.Contains(s, "[log level] handler file level changed from=INFO to=WARN") {
+		t.Error("level change log error:", s)
+	}
+}
+
+func TestLevelRace(t *testing.T) {
+	logger := NewLogger()
+	logger.SetLogger("file", map[string]interface{}{
+		"path": filepath.Join(t.TempDir(), "race.log"),
+	})
+	h, err := NewLevelHandler(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("info %d", j)
+				if i == 0 {
+					serveLevel(h, http.MethodPut, `{"level":"info","handlers":{"file":"debug"}}`)
+				} else if i == 1 {
+					logger.SetLevel(DebugLevel)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	logger.Close(context.Background())
+}
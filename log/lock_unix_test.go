@@ -0,0 +1,156 @@
+//go:build unix
+
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newSharedHandler(t *testing.T, path string, maxFileSize int64) *fileHandler {
+	c := DefaultFileConfig()
+	c.Path = path
+	c.IsCompress = false
+	c.Shared = true
+	c.MaxFileSize = maxFileSize
+	h, err := NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h.(*fileHandler)
+}
+
+//readLines returns the lines of the log file and its backups
+func readLines(t *testing.T, dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "app.log*"))
+	var lines []string
+	for _, file := range files {
+		if strings.HasSuffix(file, ".lock") {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+	}
+	return lines
+}
+
+func TestSharedRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	a := newSharedHandler(t, path, 100*MB)
+	b := newSharedHandler(t, path, 100*MB)
+	write := func(h *fileHandler, mesg string) {
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: mesg})
+	}
+	write(a, "a1")
+	write(b, "b1")
+
+	//the file of a is due
+	a.mu.Lock()
+	a.setRotateTime(time.Now().Add(-a.rotateInterval - time.Second))
+	a.mu.Unlock()
+	write(a, "a2")
+	write(b, "b2")
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "app.log-*.log"))
+	if len(backups) != 1 {
+		t.Fatal("backups expect 1 but is", backups)
+	}
+	fb, _ := os.ReadFile(backups[0])
+	if s := string(fb); !strings.Contains(s, "] a1\n") || !strings.Contains(s, "] b1\n") {
+		t.Error("backup content error:", s)
+	}
+	fb, _ = os.ReadFile(path)
+	if s := string(fb); !strings.Contains(s, "] a2\n") || !strings.HasSuffix(s, "] b2\n") {
+		t.Error("new file content error:", s)
+	}
+}
+
+func TestSharedConcurrentRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	const writers, lines = 4, 200
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		h := newSharedHandler(t, path, 2*KB)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: fmt.Sprintf("writer %d line %d end", i, j)})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got := readLines(t, dir)
+	if len(got) != writers*lines {
+		t.Errorf("lines expect %d but is %d", writers*lines, len(got))
+	}
+	for _, line := range got {
+		if !strings.HasSuffix(line, " end") || strings.Count(line, "writer") != 1 {
+			t.Fatal("interleaved line:", line)
+		}
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "app.log-*.log")); len(backups) < 2 {
+		t.Error("file is not rotated:", backups)
+	}
+}
+
+func TestSharedCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	newHandler := func() *fileHandler {
+		c := DefaultFileConfig()
+		c.Path = path
+		c.Shared = true
+		h, err := NewFileHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { h.Close() })
+		return h.(*fileHandler)
+	}
+	a, b := newHandler(), newHandler()
+	write := func(h *fileHandler, mesg string) {
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: mesg})
+	}
+	rotate := func() {
+		a.mu.Lock()
+		a.setRotateTime(time.Now().Add(-a.rotateInterval - time.Second))
+		a.mu.Unlock()
+		write(a, "a")
+		a.compressing.Wait()
+	}
+	write(b, "b1")
+
+	//b may still append to the new backup, so it is kept as it is
+	rotate()
+	if backups, _ := filepath.Glob(filepath.Join(dir, "app.log-*.gz")); len(backups) != 0 {
+		t.Fatal("new backup is compressed:", backups)
+	}
+	write(b, "b2")
+
+	//the backup of the previous rotation is compressed on the next one
+	rotate()
+	if backups, _ := filepath.Glob(filepath.Join(dir, "app.log-*.gz")); len(backups) != 1 {
+		t.Error("compressed backups expect 1 but is", backups)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "app.log-*.log")); len(backups) != 1 {
+		t.Error("uncompressed backups expect 1 but is", backups)
+	}
+}
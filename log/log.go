@@ -1,12 +1,18 @@
 package log
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,340 +22,836 @@ import (
 //add compress
 
 const log_output_buffer = 1024
+const drop_report_interval = 10 * time.Second
 const date_format = "2006-01-02"
 
 const (
-	DebugLevel = iota
+	TraceLevel = iota - 1
+	DebugLevel
 	InfoLevel
 	WarnLevel
 	ErrorLevel
 	FatalLevel
+	//PanicLevel is above FatalLevel to keep the numbers of the other levels
+	PanicLevel
 )
 
-var lock sync.RWMutex
-
-type logMesg struct {
-	Level int
-	Mesg  string
+var ErrLoggerClosed = errors.New("logger is closed")
+
+//ErrUnknownLogger is returned for a Logger not created by NewLogger
+//where the internals of the async logger are needed
+var ErrUnknownLogger = errors.New("Unknown logger, must be created by NewLogger")
+
+//LogMesg is a message passed to the handlers,
+//handlers must not modify it
+type LogMesg struct {
+	Level  int
+	Time   time.Time
+	Name   string //name of the logger, see Named
+	Mesg   string
+	Fields []Field
+	PC     uintptr //pc of the caller, see Caller
+	Stack  string  //stack trace, set only if a handler wants it, see StackHandler
+
+	suppressed bool //by sampling, written to the handlers not sampled only
+	summary    bool //of sampling, written to the sampled handlers only
 }
 
-type loggerHandler interface {
+//Handler writes messages to an output, Write is called by the logger goroutine,
+//but may be called concurrently with the overflow policy OverflowSync,
+//Rotate is called before a message is queued
+type Handler interface {
 	Setup(config map[string]interface{}) error
-	Write(mesg *logMesg)
+	Write(mesg *LogMesg)
 	Rotate()
+	Flush() error
+	Close() error
 }
 
 //no need add newline after msg
+//args not consumed by the verbs of format are key/value pairs,
+//e.g. Info("user %s login", name, "ip", ip)
 type Logger interface {
+	//SetLogger adds a handler of the registered type, config["name"] names
+	//the instance (default handlerType), a handler of the same name is replaced
 	SetLogger(handlerType string, config map[string]interface{}) error
+	//AddHandler adds a handler created by NewFileHandler etc
+	AddHandler(name string, handler Handler) error
 	SetLevel(level int)
+	//With returns a logger sharing the handlers of this one
+	//which attaches the key/value pairs to every message
+	With(kv ...interface{}) Logger
+	//Named returns a logger sharing the handlers of this one whose name is
+	//the name of this one joined by a dot, e.g. Named("dns").Named("client")
+	//is dns.client, its level may be overridden by SetModuleLevels
+	Named(name string) Logger
+	Trace(format string, v ...interface{})
 	Debug(format string, v ...interface{})
 	Info(format string, v ...interface{})
 	Warn(format string, v ...interface{})
 	Error(format string, v ...interface{})
+	//TraceContext etc attach the fields of ctx to the message,
+	//see ContextWithFields
+	TraceContext(ctx context.Context, format string, v ...interface{})
+	DebugContext(ctx context.Context, format string, v ...interface{})
+	InfoContext(ctx context.Context, format string, v ...interface{})
+	WarnContext(ctx context.Context, format string, v ...interface{})
+	ErrorContext(ctx context.Context, format string, v ...interface{})
+	//Fatal writes the message, flushes every handler, runs the exit hooks,
+	//see RegisterExitHook, then calls os.Exit(1)
 	Fatal(format string, v ...interface{})
+	//Panic writes the message, flushes every handler then panics with the message
+	Panic(format string, v ...interface{})
+	//Writer returns a writer logging every line written to it at level,
+	//see NewStdLogger and RedirectStdLog
+	Writer(level int) io.Writer
+	//Flush blocks until all queued messages are written
+	//and every handler is flushed
+	Flush() error
+	//Close drains the queue, flushes and closes every handler,
+	//further writes are dropped and Flush/Close return ErrLoggerClosed
+	Close(ctx context.Context) error
 }
 
 type LoggerImp struct {
-	level   int
-	mesgs   chan *logMesg
-	outputs map[string]loggerHandler
+	*loggerCore
+	name   string
+	fields []Field
 }
 
-func NewLogger() Logger {
-	logger := &LoggerImp{
-		mesgs:   make(chan *logMesg, log_output_buffer),
-		outputs: make(map[string]loggerHandler),
-	}
+//loggerCore is shared by a logger and all loggers derived from it
+type loggerCore struct {
+	level          atomic.Int32
+	modules        atomic.Pointer[map[string]int] //module levels
+	bufferSize     int
+	overflow       OverflowPolicy
+	reportInterval time.Duration
+	dropped        atomic.Uint64
+	stackLevel     int         //capture the stack of messages at or above it
+	wantStack      atomic.Bool //some handler writes stacks
+	sampler        *sampler    //nil if sampling is off
+	allSampled     atomic.Bool //every handler is sampled
+	mesgs          chan *LogMesg
+	flushes        chan chan error
+	done           chan struct{}
+	closeErr       error
+	clock          sync.RWMutex //guard closed and sending to mesgs
+	closed         bool
+	hlock          sync.RWMutex //guard outputs
+	outputs        []namedHandler
+}
+
+//NewLogger returns an async logger, by default the buffer holds
+//1024 messages and writes block when it is full
+func NewLogger(opts ...Option) Logger {
+	core := &loggerCore{
+		bufferSize:     log_output_buffer,
+		overflow:       OverflowBlock,
+		reportInterval: drop_report_interval,
+		stackLevel:     ErrorLevel,
+		flushes:        make(chan chan error),
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(core)
+	}
+	core.mesgs = make(chan *LogMesg, core.bufferSize)
+	logger := &LoggerImp{loggerCore: core}
 	go logger.run()
 	return logger
 }
 
 func (l *LoggerImp) SetLogger(handlerType string, config map[string]interface{}) error {
-	var handler loggerHandler
-	switch handlerType {
-	case "console":
-		handler = newconsoleHandler()
-	case "file":
-		handler = newfileHandler()
-	default:
-		return errors.New("Unknown log handler.")
+	l.clock.RLock()
+	defer l.clock.RUnlock()
+	if l.closed {
+		return ErrLoggerClosed
 	}
 
-	if err := handler.Setup(config); err != nil {
+	name := handlerType
+	if _name, ok := config["name"]; ok {
+		if name, ok = _name.(string); !ok {
+			return &ConfigError{Key: "name", Value: _name, Err: errors.New("must be a string")}
+		}
+	}
+	sampled := false
+	if _sampled, ok := config["sampled"]; ok {
+		var err error
+		if sampled, err = toBool(_sampled); err != nil {
+			return &ConfigError{Key: "sampled", Value: _sampled, Err: err}
+		}
+	}
+	//the keys of the logger are not passed to the handler
+	handler, err := newHandler(handlerType)
+	if err != nil {
+		return err
+	}
+	if err := handler.Setup(withoutKeys(config, "name", "sampled")); err != nil {
 		return err
 	}
-	l.outputs[handlerType] = handler
+	l.addHandler(name, handler)
+	if sampled {
+		l.SetSampled(name, true)
+	}
+	return nil
+}
+
+//AddHandler adds a handler created by NewFileHandler etc,
+//a handler of the same name is replaced
+func (l *LoggerImp) AddHandler(name string, handler Handler) error {
+	l.clock.RLock()
+	defer l.clock.RUnlock()
+	if l.closed {
+		return ErrLoggerClosed
+	}
+	l.addHandler(name, handler)
+	return nil
+}
+
+//Handler returns the handler named name, nil if there is none
+func (l *LoggerImp) Handler(name string) Handler {
+	l.hlock.RLock()
+	defer l.hlock.RUnlock()
+	for _, output := range l.outputs {
+		if output.name == name {
+			return output.handler
+		}
+	}
 	return nil
 }
 
+//addHandler adds or replaces the handler named name,
+//the outputs are copied so a snapshot of them is never modified
+func (l *LoggerImp) addHandler(name string, handler Handler) {
+	l.hlock.Lock()
+	var old Handler
+	outputs := make([]namedHandler, 0, len(l.outputs)+1)
+	for _, output := range l.outputs {
+		if output.name == name {
+			old = output.handler
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+	l.setOutputs(append(outputs, namedHandler{name: name, handler: handler}))
+	l.hlock.Unlock()
+	if old != nil {
+		old.Close()
+	}
+}
+
+//setOutputs replaces the outputs, caller must hold hlock
+func (l *LoggerImp) setOutputs(outputs []namedHandler) {
+	wantStack, allSampled := false, true
+	for _, output := range outputs {
+		if h, ok := output.handler.(StackHandler); ok && h.WantStack() {
+			wantStack = true
+		}
+		allSampled = allSampled && output.sampled
+	}
+	l.outputs = outputs
+	l.wantStack.Store(wantStack)
+	l.allSampled.Store(allSampled)
+}
+
 func (l *LoggerImp) SetLevel(level int) {
-	l.level = level
+	l.level.Store(int32(level))
+}
+
+func (l *LoggerImp) Level() int {
+	return int(l.level.Load())
+}
+
+func (l *LoggerImp) With(kv ...interface{}) Logger {
+	fields := make([]Field, 0, len(l.fields)+len(kv)/2)
+	fields = append(fields, l.fields...)
+	fields = append(fields, argsToFields(kv)...)
+	return &LoggerImp{
+		loggerCore: l.loggerCore,
+		name:       l.name,
+		fields:     fields,
+	}
+}
+
+func (l *LoggerImp) Named(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return &LoggerImp{
+		loggerCore: l.loggerCore,
+		name:       name,
+		fields:     l.fields,
+	}
 }
 
 func (l *LoggerImp) run() {
+	defer close(l.done)
+	var report <-chan time.Time
+	if (l.overflow.drops() || l.sampler != nil) && l.reportInterval > 0 {
+		ticker := time.NewTicker(l.reportInterval)
+		defer ticker.Stop()
+		report = ticker.C
+	}
+	var reported uint64
+	for {
+		select {
+		case mesg, ok := <-l.mesgs:
+			if !ok {
+				l.reportDropped(&reported)
+				l.reportSuppressed()
+				l.closeErr = l.closeHandlers()
+				return
+			}
+			l.dispatch(mesg)
+		case errc := <-l.flushes:
+			l.drain()
+			errc <- l.flushHandlers()
+		case <-report:
+			l.reportDropped(&reported)
+			l.reportSuppressed()
+		}
+	}
+}
+
+func (l *LoggerImp) dispatch(mesg *LogMesg) {
+	l.hlock.RLock()
+	defer l.hlock.RUnlock()
+	for _, output := range l.outputs {
+		if output.sampled && mesg.suppressed || !output.sampled && mesg.summary {
+			continue
+		}
+		output.handler.Write(mesg)
+	}
+}
+
+//drain writes the messages already queued without blocking
+func (l *LoggerImp) drain() {
 	for {
 		select {
-		case mesg := <-l.mesgs:
-			for _, handler := range l.outputs {
-				handler.Write(mesg)
+		case mesg, ok := <-l.mesgs:
+			if !ok {
+				return
 			}
+			l.dispatch(mesg)
+		default:
+			return
+		}
+	}
+}
+
+func (l *LoggerImp) flushHandlers() error {
+	l.hlock.RLock()
+	defer l.hlock.RUnlock()
+	var first error
+	for _, output := range l.outputs {
+		if err := output.handler.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (l *LoggerImp) closeHandlers() error {
+	l.hlock.RLock()
+	defer l.hlock.RUnlock()
+	var first error
+	for _, output := range l.outputs {
+		if err := output.handler.Flush(); err != nil && first == nil {
+			first = err
 		}
+		if err := output.handler.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (l *LoggerImp) Flush() error {
+	l.clock.RLock()
+	if l.closed {
+		l.clock.RUnlock()
+		return ErrLoggerClosed
+	}
+	errc := make(chan error, 1)
+	l.flushes <- errc
+	l.clock.RUnlock()
+	return <-errc
+}
+
+func (l *LoggerImp) Close(ctx context.Context) error {
+	l.clock.Lock()
+	if l.closed {
+		l.clock.Unlock()
+		return ErrLoggerClosed
+	}
+	l.closed = true
+	close(l.mesgs)
+	l.clock.Unlock()
+
+	select {
+	case <-l.done:
+		return l.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func (l *LoggerImp) writeMesg(mesg string, level int) {
-	if l.level > level {
+//writeMesg logs the message with the fields of ctx, see ContextWithFields
+func (l *LoggerImp) writeMesg(ctx context.Context, level int, format string, v []interface{}) {
+	if l.moduleLevel() > level {
 		return
 	}
+	suppressed, ok := l.sample(level, format)
+	if !ok {
+		return
+	}
+	//skip writeMesg and the exported method
+	pc := callerPC(2)
+	mesg, fields := formatArgs(format, v)
+	if cf := FieldsFromContext(ctx); len(cf) > 0 {
+		fields = append(cf[:len(cf):len(cf)], fields...)
+	}
+	lm := l.newMesg(level, time.Now(), pc, mesg, fields, v)
+	lm.suppressed = suppressed
+	l.enqueue(lm)
+}
 
-	for _, handler := range l.outputs {
-		handler.Rotate()
+//newMesg returns mesg with the fields of l followed by fields,
+//pc is the caller and args are searched for errors with a stack
+func (l *LoggerImp) newMesg(level int, t time.Time, pc uintptr, mesg string, fields []Field, args []interface{}) *LogMesg {
+	lm := &LogMesg{
+		Level:  level,
+		Time:   t,
+		Name:   l.name,
+		Mesg:   mesg,
+		Fields: l.fields,
+		PC:     pc,
+	}
+	if len(fields) > 0 {
+		lm.Fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	}
+	if l.wantStack.Load() {
+		lm.Stack = errorStack(args, lm.Fields)
+		if lm.Stack == "" && level >= l.stackLevel {
+			lm.Stack = goroutineStack(pc)
+		}
 	}
+	return lm
+}
 
-	lm := &logMesg{
-		Level: level,
-		Mesg:  mesg,
+func (l *LoggerImp) enqueue(lm *LogMesg) error {
+	l.clock.RLock()
+	defer l.clock.RUnlock()
+	if l.closed {
+		return ErrLoggerClosed
+	}
+
+	l.hlock.RLock()
+	for _, output := range l.outputs {
+		output.handler.Rotate()
+	}
+	l.hlock.RUnlock()
+
+	if !l.send(lm) && l.overflow == OverflowSync {
+		l.dispatch(lm)
 	}
+	return nil
+}
 
-	lock.RLock()
-	defer lock.RUnlock()
-	l.mesgs <- lm
+func (l *LoggerImp) Trace(format string, v ...interface{}) {
+	l.writeMesg(context.Background(), TraceLevel, format, v)
 }
 
 func (l *LoggerImp) Debug(format string, v ...interface{}) {
-	mesg := fmt.Sprintf("[DEBUG] "+format, v...)
-	l.writeMesg(mesg, DebugLevel)
+	l.writeMesg(context.Background(), DebugLevel, format, v)
 }
 
 func (l *LoggerImp) Info(format string, v ...interface{}) {
-	mesg := fmt.Sprintf("[INFO] "+format, v...)
-	l.writeMesg(mesg, InfoLevel)
+	l.writeMesg(context.Background(), InfoLevel, format, v)
 }
 
 func (l *LoggerImp) Warn(format string, v ...interface{}) {
-	mesg := fmt.Sprintf("[WARN] "+format, v...)
-	l.writeMesg(mesg, WarnLevel)
+	l.writeMesg(context.Background(), WarnLevel, format, v)
 }
 
 func (l *LoggerImp) Error(format string, v ...interface{}) {
-	mesg := fmt.Sprintf("[ERROR] "+format, v...)
-	l.writeMesg(mesg, ErrorLevel)
+	l.writeMesg(context.Background(), ErrorLevel, format, v)
+}
+
+func (l *LoggerImp) TraceContext(ctx context.Context, format string, v ...interface{}) {
+	l.writeMesg(ctx, TraceLevel, format, v)
+}
+
+func (l *LoggerImp) DebugContext(ctx context.Context, format string, v ...interface{}) {
+	l.writeMesg(ctx, DebugLevel, format, v)
+}
+
+func (l *LoggerImp) InfoContext(ctx context.Context, format string, v ...interface{}) {
+	l.writeMesg(ctx, InfoLevel, format, v)
+}
+
+func (l *LoggerImp) WarnContext(ctx context.Context, format string, v ...interface{}) {
+	l.writeMesg(ctx, WarnLevel, format, v)
+}
+
+func (l *LoggerImp) ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	l.writeMesg(ctx, ErrorLevel, format, v)
 }
 
 func (l *LoggerImp) Fatal(format string, v ...interface{}) {
-	mesg := fmt.Sprintf("[FATAL] "+format, v...)
-	l.writeMesg(mesg, FatalLevel)
+	l.writeMesg(context.Background(), FatalLevel, format, v)
+	l.Flush()
+	exit()
+}
+
+func (l *LoggerImp) Panic(format string, v ...interface{}) {
+	l.writeMesg(context.Background(), PanicLevel, format, v)
+	l.Flush()
+	mesg, _ := formatArgs(format, v)
+	panic(mesg)
 }
 
+//consoleHandler writes to stdout, and WARN and above to stderr if configured
 type consoleHandler struct {
-	level  int
-	logger *log.Logger
+	handlerLevel
+	stdout   consoleOutput
+	stderr   consoleOutput
+	toStderr bool
+	stack    bool
+}
+
+//consoleOutput has its own encoder as color depends on the file
+type consoleOutput struct {
+	logger  *log.Logger
+	encoder encoder
 }
 
-func newconsoleHandler() loggerHandler {
+func newConsoleOutput(f *os.File, c ConsoleConfig) consoleOutput {
+	encoder, _ := newEncoder(c.Format, encodeOptions{
+		caller:     c.Caller,
+		stack:      c.Stack,
+		timeLayout: timeLayout(c.TimeFormat),
+		utc:        c.UTC,
+		color:      useColor(c.Color, f),
+		align:      c.Align,
+	})
+	return consoleOutput{logger: log.New(f, "", 0), encoder: encoder}
+}
+
+func newconsoleHandler() Handler {
 	return new(consoleHandler)
 }
 
+//NewConsoleHandler returns a console handler to add by AddHandler
+func NewConsoleHandler(c ConsoleConfig) (Handler, error) {
+	h := new(consoleHandler)
+	if err := h.setup(c); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
 func (h *consoleHandler) Setup(config map[string]interface{}) error {
-	if _level, ok := config["level"]; ok {
-		level := _level.(int)
-		h.level = level
+	c := DefaultConsoleConfig()
+	if err := DecodeConfig(config, &c); err != nil {
+		return err
 	}
-	h.logger = log.New(os.Stdout, "", log.LstdFlags)
+	return h.setup(c)
+}
+
+func (h *consoleHandler) setup(c ConsoleConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	h.SetLevel(c.Level)
+	h.stdout = newConsoleOutput(os.Stdout, c)
+	h.stderr = newConsoleOutput(os.Stderr, c)
+	h.toStderr = c.Stderr
+	h.stack = c.Stack
 	return nil
 }
 
-func (h *consoleHandler) Write(lm *logMesg) {
-	if h.level <= lm.Level {
-		h.logger.Println(lm.Mesg)
+func (h *consoleHandler) Write(lm *LogMesg) {
+	if h.enabled(lm.Level) {
+		out := &h.stdout
+		if h.toStderr && lm.Level >= WarnLevel {
+			out = &h.stderr
+		}
+		var buf bytes.Buffer
+		out.encoder.encode(&buf, lm)
+		out.logger.Print(buf.String())
 	}
 }
 
+func (h *consoleHandler) WantStack() bool {
+	return h.stack
+}
+
 func (h *consoleHandler) Rotate() {
 	//do nothing
 }
 
+func (h *consoleHandler) Flush() error {
+	return nil
+}
+
+func (h *consoleHandler) Close() error {
+	return nil
+}
+
 type fileHandler struct {
+	handlerLevel
 	logger         *log.Logger
+	encoder        encoder
+	stack          bool
+	maxLevel       int
 	fileDesc       *os.File
-	level          int
 	logTime        int64
-	fileName       string        //absolute path
-	isCompress     bool          //default true
-	isRollingFile  bool          //default true
-	maxRollingTime time.Duration //default 7days
-	maxRollingNum  int           //default 7, mean one day one file
-	maxFileSize    int64         //default 100MB
-	checkInterval  time.Duration //check if del old log files, default 45m
-	rotateInterval time.Duration //maxRollingTime / maxRollingNum
-	preRotateTime  time.Time
-}
-
-func newfileHandler() loggerHandler {
+	fileName       string     //absolute path
+	compressor     Compressor //nil if the rotated files are kept as they are
+	compressLevel  int
+	compressing    sync.WaitGroup //background compression of the rotated files
+	isRollingFile  bool           //default true
+	maxRollingTime time.Duration  //default 7days
+	maxRollingNum  int            //default 7, mean one day one file
+	maxFileSize    int64          //default 100MB
+	maxBackups     int            //0 means no limit
+	maxTotalSize   int64          //of the backups, 0 means no limit
+	dryRun         bool           //log the old files instead of deleting them
+	shared         bool           //several processes write the file
+	externalRotate bool           //rotated by an external tool like logrotate
+	checkInterval  time.Duration  //check if del old log files, default 45m
+	rotateInterval time.Duration  //maxRollingTime / maxRollingNum
+	schedule       schedule       //rotate at wall clock boundaries instead of rotateInterval
+	preRotateTime  time.Time      //the time the current file started
+	nextRotate     time.Time      //by schedule
+	quit           chan struct{}
+	mu             sync.Mutex //guard the file, Write may be called concurrently
+}
+
+func newfileHandler() Handler {
 	return new(fileHandler)
 }
 
+//NewFileHandler returns a file handler to add by AddHandler
+func NewFileHandler(c FileConfig) (Handler, error) {
+	h := new(fileHandler)
+	if err := h.setup(c); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
 func (h *fileHandler) Setup(config map[string]interface{}) error {
-	if level, ok := config["level"]; ok {
-		h.level = level.(int)
+	c := DefaultFileConfig()
+	if err := DecodeConfig(config, &c); err != nil {
+		return err
 	}
-	if compress, ok := config["isCompress"]; ok {
-		h.isCompress = compress.(bool)
-	} else {
-		h.isCompress = true
+	return h.setup(c)
+}
+
+func (h *fileHandler) setup(c FileConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
 	}
-	if rolling, ok := config["isRollingFile"]; ok {
-		h.isRollingFile = rolling.(bool)
-	} else {
-		h.isRollingFile = true
+	h.SetLevel(c.Level)
+	h.encoder, _ = newEncoder(c.Format, encodeOptions{caller: c.Caller, stack: c.Stack})
+	h.stack = c.Stack
+	h.maxLevel = c.MaxLevel
+	h.compressor = nil
+	if c.IsCompress {
+		if cp, _ := getCompressor(c.Compression); cp.Ext() != "" {
+			h.compressor = cp
+		}
 	}
-	if roll, ok := config["maxRollingTime"]; ok {
-		h.maxRollingTime = roll.(time.Duration)
-	} else {
-		h.maxRollingTime = 7 * 24 * time.Hour
+	h.compressLevel = c.CompressLevel
+	h.isRollingFile = c.IsRollingFile
+	h.maxRollingTime = c.MaxRollingTime
+	h.maxRollingNum = c.MaxRollingNum
+	h.maxFileSize = c.MaxFileSize
+	h.maxBackups = c.MaxBackups
+	h.maxTotalSize = c.MaxTotalSize
+	h.dryRun = c.DryRun
+	h.shared = c.Shared
+	h.externalRotate = c.ExternalRotate
+	h.rotateInterval = h.maxRollingTime / time.Duration(h.maxRollingNum)
+	if c.RotateSchedule != "" {
+		h.schedule, _ = parseSchedule(c.RotateSchedule)
 	}
-	if roll, ok := config["maxRollingNum"]; ok {
-		h.maxRollingNum = roll.(int)
+	h.checkInterval = c.CheckInterval
+
+	h.fileName, _ = filepath.Abs(c.Path)
+	if info, exist := FileExists(h.fileName); exist {
+		h.setRotateTime(info.ModTime())
 	} else {
-		h.maxRollingNum = 7
+		h.setRotateTime(time.Now())
 	}
-	if size, ok := config["maxFileSize"]; ok {
-		h.maxFileSize = size.(int64)
-	} else {
-		h.maxFileSize = 100 * MB //100MB
+	if err := h.newLogFile(); err != nil {
+		return err
 	}
-	h.rotateInterval = h.maxRollingTime / time.Duration(h.maxRollingNum)
-	if interval, ok := config["checkInterval"]; ok {
-		h.checkInterval = interval.(time.Duration)
-	} else {
-		h.checkInterval = 45 * time.Minute
-	}
-
-	if file, ok := config["path"]; ok {
-		h.fileName, _ = filepath.Abs(file.(string))
-		info, exist := FileExists(h.fileName)
-		if exist {
-			h.preRotateTime = info.ModTime()
-			if h.isRollingFile {
-				if h.isRotate() {
-					h.rotate()
-				} else {
-					//append
-					if err := h.newLogFile(true); err != nil {
-						return err
-					}
-				}
-			} else {
-				//append
-				if err := h.newLogFile(true); err != nil {
-					return err
-				}
-			}
-		} else {
-			h.preRotateTime = time.Now()
-			if err := h.newLogFile(false); err != nil {
-				return err
-			}
+	if h.isRollingFile && !h.externalRotate {
+		if err := h.checkRotate(); err != nil {
+			h.fileDesc.Close()
+			return err
 		}
-	} else {
-		return errors.New("Logger must config log path")
 	}
 
-	go h.logRolling()
+	h.quit = make(chan struct{})
+	go h.logRolling(h.quit)
 
 	return nil
 }
 
-func (h *fileHandler) Write(lm *logMesg) {
+//Write rotates the file first if it is due, or reopens it if it is
+//moved by an external tool, h.mu serializes the writes, the rotation
+//and the rolling goroutine of this file only
+func (h *fileHandler) Write(lm *LogMesg) {
+	if !h.enabled(lm.Level) || lm.Level > h.maxLevel {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	if h.logger == nil {
 		return
 	}
 
-	if h.level <= lm.Level {
-		h.logger.Println(lm.Mesg)
+	var err error
+	if h.externalRotate {
+		_, err = h.reopenIfMoved()
+	} else if h.isRollingFile {
+		err = h.checkRotate()
+	}
+	if err != nil {
+		//keep writing to the current file
+		if h.fileDesc == nil {
+			h.newLogFile()
+		}
+		h.print(&LogMesg{
+			Level: ErrorLevel,
+			Time:  time.Now(),
+			Mesg:  fmt.Sprintf("rotate log file err %s", err),
+		})
 	}
+	h.print(lm)
 }
 
-func (h *fileHandler) write(level int, format string, v ...interface{}) {
+//print writes lm, caller must hold h.mu
+func (h *fileHandler) print(lm *LogMesg) {
 	if h.logger == nil {
 		return
 	}
+	var buf bytes.Buffer
+	h.encoder.encode(&buf, lm)
+	h.logger.Print(buf.String())
+}
 
-	if h.level <= level {
-		msg := fmt.Sprintf("[DEBUG] "+format, v...)
-		h.logger.Println(msg)
-	}
+func (h *fileHandler) write(level int, format string, v ...interface{}) {
+	h.Write(&LogMesg{
+		Level: level,
+		Time:  time.Now(),
+		Mesg:  fmt.Sprintf(format, v...),
+	})
 }
 
-func (h *fileHandler) newLogFile(append bool) error {
-	flag := os.O_CREATE | os.O_WRONLY
-	if append {
-		flag = flag | os.O_APPEND
-	} else {
-		flag = flag | os.O_TRUNC
-	}
-	output, err := os.OpenFile(h.fileName, flag, 0644)
+//newLogFile opens the file for appending, so the records of
+//several processes sharing the file never overwrite each other
+func (h *fileHandler) newLogFile() error {
+	output, err := os.OpenFile(h.fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
 	h.fileDesc = output
-	h.logger = log.New(output, "", log.LstdFlags) //Lshortfile
+	h.logger = log.New(output, "", 0)
 	return nil
 }
 
+func (h *fileHandler) WantStack() bool {
+	return h.stack
+}
+
 func (h *fileHandler) Rotate() {
-	if !h.isRollingFile {
+	//rotation is checked in Write
+}
+
+func (h *fileHandler) logRolling(quit <-chan struct{}) {
+	if !h.isRollingFile || h.externalRotate {
 		return
 	}
-	//rolling files, compress or rename
-	if h.isRotate() {
-		if err := h.rotate(); err != nil {
-			h.write(ErrorLevel, "rotate log file err %s", err)
+	h.delOldFiles()
+	ticker := time.NewTicker(h.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.delOldFiles()
+		case <-quit:
+			return
 		}
 	}
 }
 
-func (h *fileHandler) logRolling() {
-	if !h.isRollingFile {
-		return
+//backupExists returns true if the backup of name exists, compressed or not
+func (h *fileHandler) backupExists(name string) bool {
+	if _, exist := FileExists(name + ".log"); exist {
+		return true
 	}
-	h.delOldFiles()
-	for range time.Tick(h.checkInterval) {
-		h.delOldFiles()
+	if h.compressor == nil {
+		return false
 	}
+	_, exist := FileExists(name + h.compressor.Ext())
+	return exist
 }
 
-func (h *fileHandler) delOldFiles() error {
-	//delete old log files
-	dir, _ := filepath.Split(h.fileName)
-	var pattern string
-	if h.isCompress {
-		pattern = "*.gz"
-	} else {
-		pattern = "*.log"
-	}
-	now := time.Now()
-	bt := now.Add(-h.maxRollingTime)
-	if dir == "" {
-		dir = "."
+//Reopen reopens the file of the path, the current file is kept on error
+func (h *fileHandler) Reopen() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.logger == nil {
+		return nil
 	}
-	delFiles, err := Glob(dir, pattern, bt)
-	if err != nil {
-		h.write(ErrorLevel, "[rolling log] find old log file in dir %s err %s", dir, err)
+	old := h.fileDesc
+	if err := h.newLogFile(); err != nil {
 		return err
 	}
-	if len(delFiles) > 0 {
-		h.write(DebugLevel, "[rolling log] find %d old log file(s) in dir %s", len(delFiles), dir)
-		for _, file := range delFiles {
-			if err := os.Remove(file); err == nil {
-				h.write(DebugLevel, "delete log file %s done", filepath.Base(file))
-			}
-		}
+	if old != nil {
+		old.Close()
 	}
+	h.setRotateTime(time.Now())
 	return nil
 }
 
+func (h *fileHandler) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fileDesc == nil {
+		return nil
+	}
+	return h.fileDesc.Sync()
+}
+
+//Close closes the file and waits for the background compression
+func (h *fileHandler) Close() error {
+	err := h.close()
+	h.compressing.Wait()
+	return err
+}
+
+func (h *fileHandler) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.quit != nil {
+		close(h.quit)
+		h.quit = nil
+	}
+	h.logger = nil
+	if h.fileDesc == nil {
+		return nil
+	}
+	err := h.fileDesc.Close()
+	h.fileDesc = nil
+	return err
+}
+
 func (h *fileHandler) isRotate() bool {
 	//check file size
 	info, exist := FileExists(h.fileName)
@@ -360,36 +862,154 @@ func (h *fileHandler) isRotate() bool {
 	}
 	//check file time
 	now := time.Now()
+	if h.schedule != nil {
+		return !now.Before(h.nextRotate)
+	}
 	next := h.preRotateTime.Add(h.rotateInterval)
 	gap := now.Sub(next)
 	return gap > 0 || (gap < 0 && gap > -100*time.Millisecond)
 }
 
-//assume fileName is exists
+//checkRotate rotates the file if it is due, caller must hold h.mu,
+//with shared on only the process holding the lock file rotates,
+//the others find the file moved and reopen it
+func (h *fileHandler) checkRotate() error {
+	if h.shared {
+		if moved, err := h.reopenIfMoved(); moved || err != nil {
+			return err
+		}
+	}
+	if !h.isRotate() {
+		return nil
+	}
+	if !h.shared {
+		return h.rotate()
+	}
+	unlock, err := lockFile(h.fileName + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	//another process may have rotated while waiting for the lock
+	if moved, err := h.reopenIfMoved(); moved || err != nil {
+		return err
+	}
+	return h.rotate()
+}
+
+//reopenIfMoved reopens the file if the path is not the open file any more
+func (h *fileHandler) reopenIfMoved() (bool, error) {
+	info, err := h.fileDesc.Stat()
+	if err != nil {
+		return false, err
+	}
+	pinfo, err := os.Stat(h.fileName)
+	if err == nil && os.SameFile(info, pinfo) {
+		return false, nil
+	}
+	if err != nil && h.externalRotate {
+		//the external tool creates the file, or it is reopened by Reopen
+		return false, nil
+	}
+	h.fileDesc.Close()
+	h.fileDesc = nil
+	if err := h.newLogFile(); err != nil {
+		return true, err
+	}
+	h.setRotateTime(time.Now())
+	return true, nil
+}
+
+//rotate renames the file then compresses it in the background,
+//caller must hold h.mu once the handler is in use
 func (h *fileHandler) rotate() error {
-	lock.Lock()
-	defer lock.Unlock()
 	if h.fileDesc != nil {
 		if err := h.fileDesc.Close(); err != nil {
 			return err
 		}
 		h.fileDesc = nil
 	}
-	now := time.Now()
-	suffix := "-" + now.Format("20060102-150405")
-	if h.isCompress {
-		if err := Compress(h.fileName, suffix, true); err != nil {
-			return err
-		}
-	} else {
-		if err := os.Rename(h.fileName, h.fileName+suffix+".log"); err != nil {
-			return err
-		}
+	backup := h.fileName + h.backupSuffix() + ".log"
+	if err := os.Rename(h.fileName, backup); err != nil {
+		return err
 	}
-	if err := h.newLogFile(false); err != nil {
+	if err := h.newLogFile(); err != nil {
 		return err
 	}
-	h.preRotateTime = time.Now()
+	h.setRotateTime(time.Now())
 	//h.write(DebugLevel, "[rolling log] rotate to a new log file")
+	if h.compressor != nil {
+		pending := []string{backup}
+		if h.shared {
+			//a process sharing the file may still append to the backup
+			//until it finds the file moved on its next write, so only
+			//the backups of the previous rotations are compressed
+			pending = h.uncompressed(backup)
+		}
+		if len(pending) > 0 {
+			h.compressing.Add(1)
+			go h.compress(pending)
+		}
+	}
 	return nil
 }
+
+//uncompressed returns the backups not compressed yet except current
+func (h *fileHandler) uncompressed(current string) []string {
+	backups, err := h.backups()
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, b := range backups {
+		if b.path != current && strings.HasSuffix(b.path, ".log") {
+			files = append(files, b.path)
+		}
+	}
+	return files
+}
+
+//compress compresses the rotated files, a file is kept uncompressed on error
+func (h *fileHandler) compress(backups []string) {
+	defer h.compressing.Done()
+	for _, backup := range backups {
+		err := compressFile(backup, h.compressor, h.compressLevel)
+		//another process sharing the file may have compressed it
+		if err != nil && !os.IsNotExist(err) {
+			h.write(ErrorLevel, "[rolling log] compress log file %s err %s", filepath.Base(backup), err)
+		}
+	}
+}
+
+//setRotateTime sets the time the current file started
+func (h *fileHandler) setRotateTime(t time.Time) {
+	h.preRotateTime = t
+	if h.schedule != nil {
+		h.nextRotate = h.schedule.next(t)
+	}
+}
+
+//backupSuffix returns the suffix of the rotated file which is the period
+//the current file covers, a counter is added if the name is taken,
+//e.g. -20060102 and -20060102.1 of the daily schedule
+func (h *fileHandler) backupSuffix() string {
+	var name string
+	if h.schedule != nil {
+		//the start of the period, preRotateTime may be the time
+		//of the last write of a file left by a previous process
+		start := h.schedule.prev(h.preRotateTime)
+		if start.IsZero() {
+			start = h.preRotateTime
+		}
+		name = h.schedule.name(start)
+	} else {
+		name = h.preRotateTime.Format("20060102-150405")
+	}
+	suffix := "-" + name
+	for i := 1; ; i++ {
+		if !h.backupExists(h.fileName + suffix) {
+			return suffix
+		}
+		suffix = "-" + name + "." + strconv.Itoa(i)
+	}
+}
@@ -2,13 +2,18 @@ package log
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 )
 
 func TestLog(t *testing.T) {
+	stubExit(t)
 	logger := NewLogger()
 	logger.SetLogger("console", nil)
 	logger.SetLevel(DebugLevel)
@@ -18,10 +23,13 @@ func TestLog(t *testing.T) {
 	logger.Warn("warn")
 	logger.Error("error")
 	logger.Fatal("fatal")
-	time.Sleep(time.Second)
+	if err := logger.Close(context.Background()); err != nil {
+		t.Error("close logger error:", err)
+	}
 }
 
 func TestLogFile(t *testing.T) {
+	stubExit(t)
 	logger := NewLogger()
 	name := "test.log"
 	config := make(map[string]interface{})
@@ -41,7 +49,9 @@ func TestLogFile(t *testing.T) {
 	logger.Error("error")
 	logger.Fatal("fatal")
 
-	time.Sleep(time.Second)
+	if err := logger.Flush(); err != nil {
+		t.Error("flush logger error:", err)
+	}
 
 	if _, ok := FileExists(name); ok {
 		fd, _ := os.Open(name)
@@ -70,6 +80,7 @@ func TestLogFile(t *testing.T) {
 }
 
 func TestLogRollingFile(t *testing.T) {
+	stubExit(t)
 	logger := NewLogger()
 	config := make(map[string]interface{})
 	config["path"] = "../../log/test.log"
@@ -89,3 +100,191 @@ func TestLogRollingFile(t *testing.T) {
 		time.Sleep(1 * time.Second)
 	}
 }
+
+func TestLogFlush(t *testing.T) {
+	logger := NewLogger()
+	name := "test_flush.log"
+	defer os.Remove(name)
+	config := make(map[string]interface{})
+	config["path"] = name
+	config["isRollingFile"] = false
+	if err := logger.SetLogger("file", config); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+
+	for i := 0; i < 3*log_output_buffer; i++ {
+		logger.Info("info %d", i)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal("flush logger error:", err)
+	}
+	fd, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	line := 0
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		if !strings.HasSuffix(scanner.Text(), fmt.Sprintf("info %d", line)) {
+			t.Fatal("log file error content:", scanner.Text())
+		}
+		line++
+	}
+	if line != 3*log_output_buffer {
+		t.Error("line num of log file expect", 3*log_output_buffer, "but is", line)
+	}
+	logger.Close(context.Background())
+}
+
+func TestLogClose(t *testing.T) {
+	logger := NewLogger()
+	name := "test_close.log"
+	defer os.Remove(name)
+	config := make(map[string]interface{})
+	config["path"] = name
+	if err := logger.SetLogger("file", config); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	logger.Info("before close")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := logger.Close(ctx); err != nil {
+		t.Fatal("close logger error:", err)
+	}
+	if err := logger.Close(ctx); err != ErrLoggerClosed {
+		t.Error("close twice expect ErrLoggerClosed but is", err)
+	}
+	if err := logger.Flush(); err != ErrLoggerClosed {
+		t.Error("flush after close expect ErrLoggerClosed but is", err)
+	}
+	if err := logger.SetLogger("console", nil); err != ErrLoggerClosed {
+		t.Error("set logger after close expect ErrLoggerClosed but is", err)
+	}
+
+	//must not block even if more than the buffer is written
+	for i := 0; i < 2*log_output_buffer; i++ {
+		logger.Info("after close")
+	}
+
+	fb, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(fb); !strings.HasSuffix(s, "before close\n") || strings.Contains(s, "after close") {
+		t.Error("log file error content:", s)
+	}
+}
+
+func TestLogWith(t *testing.T) {
+	logger := NewLogger()
+	name := "test_with.log"
+	defer os.Remove(name)
+	config := make(map[string]interface{})
+	config["path"] = name
+	config["format"] = "json"
+	if err := logger.SetLogger("file", config); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+
+	child := logger.With("req_id", 42)
+	child.Info("user %s login", "bob", "ip", "10.0.0.1")
+	logger.Info("no fields")
+	logger.Close(context.Background())
+
+	fd, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var m map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatal("invalid json line:", scanner.Text())
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 2 {
+		t.Fatal("line num of log file expect = 2 but is", len(lines))
+	}
+	if lines[0]["msg"] != "user bob login" || lines[0]["level"] != "INFO" ||
+		lines[0]["req_id"] != 42.0 || lines[0]["ip"] != "10.0.0.1" {
+		t.Error("log file error content:", lines[0])
+	}
+	if _, ok := lines[1]["req_id"]; ok || lines[1]["msg"] != "no fields" {
+		t.Error("log file error content:", lines[1])
+	}
+}
+
+func TestLogLevelSplit(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewLogger()
+	logger.SetLevel(DebugLevel)
+	files := []map[string]interface{}{
+		{"name": "all", "path": filepath.Join(dir, "all.log")},
+		{"name": "access", "path": filepath.Join(dir, "access.log"), "level": "info", "maxLevel": "info"},
+		{"name": "error", "path": filepath.Join(dir, "error.log"), "level": "error"},
+	}
+	for _, config := range files {
+		if err := logger.SetLogger("file", config); err != nil {
+			t.Fatal("set logger error:", err)
+		}
+	}
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	logger.Close(context.Background())
+
+	expects := map[string][]string{
+		"all.log":    {"debug", "info", "warn", "error"},
+		"access.log": {"info"},
+		"error.log":  {"error"},
+	}
+	for name, expect := range expects {
+		fb, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(fb)), "\n")
+		if len(lines) != len(expect) {
+			t.Errorf("%s expect %v but is %q", name, expect, lines)
+			continue
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, "] "+expect[i]) {
+				t.Errorf("%s line %d expect %s but is %q", name, i, expect[i], line)
+			}
+		}
+	}
+
+	if err := logger.SetLogger("file", map[string]interface{}{"path": "x.log", "level": "error", "maxLevel": "warn"}); err == nil {
+		t.Error("max level below level expect error")
+	}
+}
+
+func TestLogRotateInWrite(t *testing.T) {
+	dir := t.TempDir()
+	c := DefaultFileConfig()
+	c.Path = filepath.Join(dir, "app.log")
+	c.MaxFileSize = 100
+	c.IsCompress = false
+	h, err := NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	for i := 0; i < 10; i++ {
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: strings.Repeat("x", 50)})
+	}
+	rotated, _ := filepath.Glob(filepath.Join(dir, "app.log-*.log"))
+	if len(rotated) == 0 {
+		t.Error("file is not rotated in Write")
+	}
+	if info, _ := FileExists(c.Path); info == nil || info.Size() > 200 {
+		t.Error("current file is not rotated")
+	}
+}
@@ -0,0 +1,135 @@
+//Package logq reads, filters and follows the files written by the file
+//handler of package log, including the rotated and compressed ones
+package logq
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hacky-DH/goLib/log"
+)
+
+var (
+	decompressorLock sync.RWMutex
+	decompressors    = map[string]func(io.Reader) (io.ReadCloser, error){
+		".gz": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		".zz": zlib.NewReader,
+		".deflate": func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		},
+	}
+)
+
+//RegisterDecompressor makes the files of ext like .gz readable,
+//for the compressors registered by log.RegisterCompressor
+func RegisterDecompressor(ext string, fn func(io.Reader) (io.ReadCloser, error)) {
+	decompressorLock.Lock()
+	defer decompressorLock.Unlock()
+	decompressors[ext] = fn
+}
+
+//decompressor returns the decompressor of the extension of name, nil if
+//the file is not compressed
+func decompressor(name string) func(io.Reader) (io.ReadCloser, error) {
+	decompressorLock.RLock()
+	defer decompressorLock.RUnlock()
+	return decompressors[filepath.Ext(name)]
+}
+
+//isBackup returns true if name is a rotated file of the log file base,
+//which is named base-suffix.log or compressed like base-suffix.gz,
+//see log.IsBackupSuffix, so the files of other handlers are not
+func isBackup(base, name string) bool {
+	if !strings.HasPrefix(name, base+"-") {
+		return false
+	}
+	suffix := name[len(base)+1:]
+	ext := filepath.Ext(suffix)
+	if ext != ".log" && decompressor(suffix) == nil {
+		return false
+	}
+	return log.IsBackupSuffix(strings.TrimSuffix(suffix, ext))
+}
+
+//Backups returns the rotated files of the log file path, the oldest first
+func Backups(path string) ([]string, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackup(base, entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			//removed meanwhile
+			continue
+		}
+		backups = append(backups, backup{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.Before(backups[j].modTime)
+		}
+		return backups[i].path < backups[j].path
+	})
+	files := make([]string, len(backups))
+	for i, b := range backups {
+		files[i] = b.path
+	}
+	return files, nil
+}
+
+//readCloser closes the decompressor and the file
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+//openFile opens name, decompressing it by its extension
+func openFile(name string) (*os.File, io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fn := decompressor(name)
+	if fn == nil {
+		return f, f, nil
+	}
+	dr, err := fn(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, &readCloser{dr, []io.Closer{dr, f}}, nil
+}
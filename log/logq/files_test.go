@@ -0,0 +1,41 @@
+package logq
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackups(t *testing.T) {
+	dir := t.TempDir()
+	//the newest last
+	names := []string{
+		"app.log-20200103-000000.gz",
+		"app.log-20200101-000000.zz",
+		"app.log-20200102-000000.log",
+	}
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, nil, 0644)
+		mtime := time.Now().Add(time.Duration(i-len(names)) * time.Hour)
+		os.Chtimes(path, mtime, mtime)
+	}
+	//not rotated files, or the files of other handlers like a memory dump
+	for _, name := range []string{"app.log", "app.log-20200104-000000.gz.123.tmp", "app.log.lock", "app.log2-20200101-000000.gz",
+		"app.log-access.log", "app.log-crash-20200101-101010.log", "app.log-20200101-audit.log"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	files, err := Backups(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expect []string
+	for _, name := range names {
+		expect = append(expect, filepath.Join(dir, name))
+	}
+	if !reflect.DeepEqual(files, expect) {
+		t.Errorf("backups expect %v but is %v", expect, files)
+	}
+}
@@ -0,0 +1,221 @@
+package logq
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+//Reader reads the records of log files in order, the last file is the
+//live file which may be followed like tail -F
+type Reader struct {
+	files    []string //to read before the live file
+	path     string   //the live file
+	next     int      //index of the next file to open, len(files) for the live file
+	file     *os.File
+	rc       io.ReadCloser
+	br       *bufio.Reader
+	live     bool   //the current file is the live file, which may grow
+	partial  string //a line without the newline yet
+	pending  *Record
+	ctx      context.Context
+	interval time.Duration //0 if not following
+}
+
+//Open returns a reader of the rotated files of the log file path,
+//the oldest first, then path itself
+func Open(path string) (*Reader, error) {
+	files, err := Backups(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(append(files, path)...), nil
+}
+
+//NewReader returns a reader of files in the order given, the last one is
+//the live file, files which do not exist are skipped
+func NewReader(files ...string) *Reader {
+	r := &Reader{ctx: context.Background()}
+	if len(files) > 0 {
+		r.files = files[:len(files)-1]
+		r.path = files[len(files)-1]
+	}
+	return r
+}
+
+//Follow makes Next wait for the records appended to the live file every
+//interval until ctx is done, when the live file is rotated, truncated or
+//created, it is reopened by its path
+func (r *Reader) Follow(ctx context.Context, interval time.Duration) {
+	r.ctx = ctx
+	r.interval = interval
+}
+
+//Next returns the next record, io.EOF at the end of the files,
+//or the error of ctx if following
+func (r *Reader) Next() (*Record, error) {
+	for {
+		line, err := r.readLine()
+		if err == nil {
+			rec := parseRecord(line)
+			if rec == nil {
+				if r.pending != nil && r.pending.Fields == nil {
+					r.pending.addLine(line)
+					continue
+				}
+				rec = orphanRecord(line)
+			}
+			out := r.pending
+			r.pending = rec
+			if out != nil {
+				return out, nil
+			}
+			continue
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+		//a record never continues in another file or after a pause
+		if r.pending != nil {
+			out := r.pending
+			r.pending = nil
+			return out, nil
+		}
+		if err := r.advance(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//readLine returns the next line of the current file without the newline,
+//io.EOF at the end of it, a last line without the newline is returned
+//only if the file does not grow any more
+func (r *Reader) readLine() (string, error) {
+	if r.br == nil {
+		return "", io.EOF
+	}
+	s, err := r.br.ReadString('\n')
+	if err == nil {
+		line := r.partial + s[:len(s)-1]
+		r.partial = ""
+		return strings.TrimSuffix(line, "\r"), nil
+	}
+	r.partial += s
+	if err == io.EOF && (!r.live || r.interval == 0) && r.partial != "" {
+		line := r.partial
+		r.partial = ""
+		return line, nil
+	}
+	return "", err
+}
+
+//advance opens the next file, or waits for the live file to change
+//if following, returns io.EOF if there is nothing more to read
+func (r *Reader) advance() error {
+	for r.next < len(r.files) {
+		name := r.files[r.next]
+		r.next++
+		if err := r.open(name, false); err == nil || !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if r.next == len(r.files) {
+		r.next++
+		if err := r.open(r.path, true); !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if r.interval == 0 {
+		r.close()
+		return io.EOF
+	}
+	for {
+		if changed, err := r.changed(); changed || err != nil {
+			return err
+		}
+		select {
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+//changed returns true if the live file has more to read, is truncated,
+//or the path is another file, which is opened after the rest of the
+//current file is read
+func (r *Reader) changed() (bool, error) {
+	if r.file == nil {
+		err := r.open(r.path, true)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	info, err := r.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	offset, err := r.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	if info.Size() > offset {
+		return true, nil
+	}
+	if !r.live {
+		//the rest of the rotated file is read
+		err := r.open(r.path, true)
+		if errors.Is(err, os.ErrNotExist) {
+			r.close()
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if info.Size() < offset {
+		//truncated like logrotate copytruncate
+		r.partial = ""
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		r.br.Reset(r.file)
+		return true, nil
+	}
+	if pinfo, err := os.Stat(r.path); err == nil && !os.SameFile(info, pinfo) {
+		//rotated, read the rest of the current file first
+		r.live = false
+		return true, nil
+	}
+	return false, nil
+}
+
+//open opens name as the current file
+func (r *Reader) open(name string, live bool) error {
+	file, rc, err := openFile(name)
+	if err != nil {
+		return err
+	}
+	r.close()
+	r.file, r.rc, r.live = file, rc, live
+	r.br = bufio.NewReader(rc)
+	return nil
+}
+
+func (r *Reader) close() error {
+	r.partial = ""
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.file, r.rc, r.br = nil, nil, nil
+	return err
+}
+
+//Close closes the current file
+func (r *Reader) Close() error {
+	return r.close()
+}
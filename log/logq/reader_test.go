@@ -0,0 +1,179 @@
+package logq
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Hacky-DH/goLib/log"
+)
+
+func writeGzip(t *testing.T, path, content string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	io.WriteString(w, content)
+	w.Close()
+	f.Close()
+}
+
+//readAll returns the raw lines of the records of r
+func readAll(t *testing.T, r *Reader) []string {
+	var raws []string
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return raws
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		raws = append(raws, rec.Raw)
+	}
+}
+
+func TestReader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeGzip(t, path+"-1.gz", "2020/01/01 00:00:00 [INFO] a\n"+
+		"2020/01/01 00:00:01 [ERROR] b\n\tstack 1\n\tstack 2\n")
+	os.WriteFile(path+"-2.log", []byte("2020/01/02 00:00:00 [INFO] c"), 0644)
+	os.WriteFile(path, []byte("\torphan\n2020/01/03 00:00:00 [INFO] d\n"), 0644)
+	r := NewReader(path+"-1.gz", path+"-missing.log", path+"-2.log", path)
+	defer r.Close()
+	expect := []string{
+		"2020/01/01 00:00:00 [INFO] a",
+		"2020/01/01 00:00:01 [ERROR] b\n\tstack 1\n\tstack 2",
+		"2020/01/02 00:00:00 [INFO] c",
+		"\torphan",
+		"2020/01/03 00:00:00 [INFO] d",
+	}
+	if got := readAll(t, r); !reflect.DeepEqual(got, expect) {
+		t.Errorf("records expect %q but is %q", expect, got)
+	}
+}
+
+func TestReadRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := log.DefaultFileConfig()
+	c.Path = filepath.Join(dir, "app.log")
+	c.MaxFileSize = 1
+	h, err := log.NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expect []string
+	for i := 0; i < 5; i++ {
+		mesg := fmt.Sprintf("line %d", i)
+		h.Write(&log.LogMesg{Level: log.InfoLevel, Time: time.Now(), Mesg: mesg})
+		expect = append(expect, mesg)
+		//the backups are ordered by time
+		time.Sleep(10 * time.Millisecond)
+	}
+	//waits for the compression
+	h.Close()
+	if backups, _ := filepath.Glob(c.Path + "-*.gz"); len(backups) == 0 {
+		t.Fatal("no compressed backups")
+	}
+
+	r, err := Open(c.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var got []string
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec.Mesg)
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("records expect %q but is %q", expect, got)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendLine := func(mesg string) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(f, "2020/01/01 00:00:00 [INFO] %s\n", mesg)
+		f.Close()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewReader(path)
+	defer r.Close()
+	r.Follow(ctx, time.Millisecond)
+	records := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			rec, err := r.Next()
+			if err != nil {
+				errs <- err
+				return
+			}
+			records <- rec.Mesg
+		}
+	}()
+	expect := func(mesg string) {
+		t.Helper()
+		select {
+		case got := <-records:
+			if got != mesg {
+				t.Errorf("record expect %s but is %s", mesg, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("wait for", mesg)
+		}
+	}
+
+	//created after following
+	appendLine("a")
+	expect("a")
+	appendLine("b")
+	expect("b")
+
+	//rotated, the rest of the old file is read first
+	os.Rename(path, path+"-1.log")
+	f, _ := os.OpenFile(path+"-1.log", os.O_WRONLY|os.O_APPEND, 0644)
+	fmt.Fprintf(f, "2020/01/01 00:00:00 [INFO] late\n")
+	f.Close()
+	appendLine("c")
+	expect("late")
+	expect("c")
+
+	//truncated like copytruncate, a file truncated and grown back
+	//to the size read before it is noticed is not detected, like tail
+	os.Truncate(path, 0)
+	time.Sleep(50 * time.Millisecond)
+	appendLine("d")
+	expect("d")
+
+	cancel()
+	select {
+	case err := <-errs:
+		if err != context.Canceled {
+			t.Error("following stops with", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("following does not stop")
+	}
+}
@@ -0,0 +1,196 @@
+package logq
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hacky-DH/goLib/log"
+)
+
+//the time layouts of the text and json encoders of package log
+const (
+	text_time_format = "2006/01/02 15:04:05"
+	json_time_format = "2006-01-02T15:04:05.000Z07:00"
+)
+
+//Record is a log record read from a file
+type Record struct {
+	Time   time.Time //zero if the lines are not a record, like a truncated stack
+	Level  int
+	Logger string //of json records, text records keep it in Mesg
+	Mesg   string
+	Stack  string
+	Raw    string                 //the lines as written without the last newline
+	Fields map[string]interface{} //all the keys of a json record, nil for text
+	level  string                 //the level name as written
+}
+
+//parseRecord parses the first line of a text or json record,
+//returns nil if line is not the start of a record
+func parseRecord(line string) *Record {
+	if strings.HasPrefix(line, "{") {
+		return parseJSONRecord(line)
+	}
+	return parseTextRecord(line)
+}
+
+//parseTextRecord parses lines like
+//2006/01/02 15:04:05 [INFO] [name] dir/file.go:12 mesg key=value
+func parseTextRecord(line string) *Record {
+	n := len(text_time_format)
+	if len(line) < n+2 || line[n:n+2] != " [" {
+		return nil
+	}
+	t, err := time.ParseInLocation(text_time_format, line[:n], time.Local)
+	if err != nil {
+		return nil
+	}
+	rest := line[n+2:]
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return nil
+	}
+	name := rest[:end]
+	level, ok := parseLevel(name)
+	if !ok {
+		return nil
+	}
+	return &Record{
+		Time:  t,
+		Level: level,
+		Mesg:  strings.TrimPrefix(rest[end+1:], " "),
+		Raw:   line,
+		level: name,
+	}
+}
+
+//parseJSONRecord parses lines like
+//{"time":"2006-01-02T15:04:05.000+08:00","level":"INFO","logger":"name","msg":"mesg"}
+func parseJSONRecord(line string) *Record {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil
+	}
+	ts, _ := fields["time"].(string)
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil
+	}
+	name, _ := fields["level"].(string)
+	level, ok := parseLevel(name)
+	if !ok {
+		return nil
+	}
+	r := &Record{
+		Time:   t,
+		Level:  level,
+		Raw:    line,
+		Fields: fields,
+		level:  name,
+	}
+	r.Logger, _ = fields["logger"].(string)
+	r.Mesg, _ = fields["msg"].(string)
+	r.Stack, _ = fields["stack"].(string)
+	return r
+}
+
+//parseLevel parses the level names written by package log like INFO or LEVEL(7)
+func parseLevel(name string) (int, bool) {
+	if strings.HasPrefix(name, "LEVEL(") && strings.HasSuffix(name, ")") {
+		n, err := strconv.Atoi(name[len("LEVEL(") : len(name)-1])
+		return n, err == nil
+	}
+	if name == "" || strings.TrimSpace(name) != name {
+		return 0, false
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return 0, false
+	}
+	level, err := log.ParseLevel(name)
+	return level, err == nil
+}
+
+//orphanRecord returns the record of a line which is not a record,
+//like the rest of a stack trace whose record is in another file
+func orphanRecord(line string) *Record {
+	return &Record{
+		Level: log.TraceLevel,
+		Mesg:  line,
+		Raw:   line,
+	}
+}
+
+//addLine adds a line following the first line of a text record,
+//which is the stack trace
+func (r *Record) addLine(line string) {
+	r.Raw += "\n" + line
+	if r.Stack != "" {
+		r.Stack += "\n"
+	}
+	r.Stack += line
+}
+
+//MarshalJSON returns the line of a json record as it is,
+//a text record is encoded like the json encoder of package log
+func (r *Record) MarshalJSON() ([]byte, error) {
+	if r.Fields != nil {
+		return []byte(r.Raw), nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(struct {
+		Time  string `json:"time,omitempty"`
+		Level string `json:"level,omitempty"`
+		Mesg  string `json:"msg"`
+		Stack string `json:"stack,omitempty"`
+	}{r.timeString(), r.level, r.Mesg, r.Stack})
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), err
+}
+
+func (r *Record) timeString() string {
+	if r.Time.IsZero() {
+		return ""
+	}
+	return r.Time.Format(json_time_format)
+}
+
+//Filter selects the records to read, start from DefaultFilter
+type Filter struct {
+	Since    time.Time //zero means no limit
+	Until    time.Time //exclusive, zero means no limit
+	Level    int       //the lowest level, default log.TraceLevel
+	Contains string    //substring of the lines of the record
+	Regexp   *regexp.Regexp
+}
+
+func DefaultFilter() Filter {
+	return Filter{Level: log.TraceLevel}
+}
+
+//Match returns true if r is selected, lines which are not a record
+//like a truncated stack are selected only without time and level limits
+func (f *Filter) Match(r *Record) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && (r.Time.IsZero() || !r.Time.Before(f.Until)) {
+		return false
+	}
+	if r.Level < f.Level || (r.Time.IsZero() && f.Level > log.TraceLevel) {
+		return false
+	}
+	if f.Contains != "" && !strings.Contains(r.Raw, f.Contains) {
+		return false
+	}
+	if f.Regexp != nil && !f.Regexp.MatchString(r.Raw) {
+		return false
+	}
+	return true
+}
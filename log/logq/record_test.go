@@ -0,0 +1,85 @@
+package logq
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Hacky-DH/goLib/log"
+)
+
+func TestParseRecord(t *testing.T) {
+	r := parseRecord("2020/01/02 15:04:05 [WARN] [db] db.go:12 slow query ms=120")
+	if r == nil {
+		t.Fatal("text record is not parsed")
+	}
+	if !r.Time.Equal(time.Date(2020, 1, 2, 15, 4, 5, 0, time.Local)) || r.Level != log.WarnLevel ||
+		r.Mesg != "[db] db.go:12 slow query ms=120" {
+		t.Errorf("text record is %+v", r)
+	}
+	r.addLine("goroutine 1 [running]:")
+	b, _ := json.Marshal(r)
+	expect := `{"time":"` + r.Time.Format(json_time_format) + `","level":"WARN","msg":"[db] db.go:12 slow query ms=120","stack":"goroutine 1 [running]:"}`
+	if string(b) != expect {
+		t.Errorf("text record json is %s", b)
+	}
+
+	line := `{"time":"2020-01-02T15:04:05.123+08:00","level":"LEVEL(7)","logger":"db","msg":"hi","n":1}`
+	r = parseRecord(line)
+	if r == nil {
+		t.Fatal("json record is not parsed")
+	}
+	if r.Time.Nanosecond() != 123e6 || r.Level != 7 || r.Logger != "db" || r.Mesg != "hi" || r.Fields["n"] != json.Number("1") {
+		t.Errorf("json record is %+v", r)
+	}
+	if b, _ := json.Marshal(r); string(b) != line {
+		t.Errorf("json record json is %s", b)
+	}
+
+	for _, line := range []string{
+		"",
+		"\tgoroutine 1 [running]:",
+		"2020/01/02 15:04:05 no level",
+		"2020/01/02 15:04:05 [1] number level",
+		"2020/13/02 15:04:05 [INFO] bad time",
+		`{"level":"INFO","msg":"no time"}`,
+		`{"time":"2020-01-02T15:04:05Z","level":"INFO"`,
+	} {
+		if r := parseRecord(line); r != nil {
+			t.Errorf("%q is parsed to %+v", line, r)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	at := time.Date(2020, 1, 2, 15, 4, 5, 0, time.Local)
+	r := &Record{Time: at, Level: log.WarnLevel, Raw: "slow query ms=120"}
+	orphan := orphanRecord("goroutine 1 [running]:")
+	cases := []struct {
+		name          string
+		filter        func(*Filter)
+		match, orphan bool
+	}{
+		{"default", func(f *Filter) {}, true, true},
+		{"since", func(f *Filter) { f.Since = at }, true, false},
+		{"since after", func(f *Filter) { f.Since = at.Add(time.Second) }, false, false},
+		{"until", func(f *Filter) { f.Until = at }, false, false},
+		{"until after", func(f *Filter) { f.Until = at.Add(time.Second) }, true, false},
+		{"level", func(f *Filter) { f.Level = log.WarnLevel }, true, false},
+		{"level above", func(f *Filter) { f.Level = log.ErrorLevel }, false, false},
+		{"contains", func(f *Filter) { f.Contains = "query" }, true, false},
+		{"regexp", func(f *Filter) { f.Regexp = regexp.MustCompile(`ms=\d+`) }, true, false},
+		{"regexp not", func(f *Filter) { f.Regexp = regexp.MustCompile(`^ms`) }, false, false},
+	}
+	for _, c := range cases {
+		f := DefaultFilter()
+		c.filter(&f)
+		if f.Match(r) != c.match {
+			t.Errorf("%s: match expect %v", c.name, c.match)
+		}
+		if f.Match(orphan) != c.orphan {
+			t.Errorf("%s: orphan match expect %v", c.name, c.orphan)
+		}
+	}
+}
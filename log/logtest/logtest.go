@@ -0,0 +1,226 @@
+//Package logtest captures the messages of package log in tests,
+//instead of reading the log files back
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Hacky-DH/goLib/log"
+)
+
+//Entry is a message recorded by Recorder
+type Entry struct {
+	Level  int
+	Time   time.Time
+	Name   string
+	Mesg   string
+	Fields map[string]interface{}
+	Stack  string
+}
+
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString("[" + log.LevelName(e.Level) + "] ")
+	if e.Name != "" {
+		b.WriteString("[" + e.Name + "] ")
+	}
+	b.WriteString(e.Mesg)
+	return b.String()
+}
+
+//New returns a logger at TRACE level which writes to a Recorder
+//and to t.Log, it is closed when the test finishes, the messages
+//flushed by the close still go to t.Log
+func New(t testing.TB, opts ...log.Option) (log.Logger, *Recorder) {
+	logger := log.NewLogger(opts...)
+	logger.SetLevel(log.TraceLevel)
+	rec := NewRecorder(logger)
+	tb := &tbHandler{t: t}
+	logger.AddHandler("recorder", rec)
+	logger.AddHandler("testing", tb)
+	t.Cleanup(func() {
+		logger.Close(context.Background())
+		tb.detach()
+	})
+	return logger, rec
+}
+
+//Recorder is a handler recording the messages in memory,
+//logger is flushed before the entries are read so every message
+//written before is recorded
+type Recorder struct {
+	logger  log.Logger
+	mu      sync.Mutex
+	entries []Entry
+}
+
+//NewRecorder returns a recorder to add to logger by AddHandler,
+//logger may be nil if the recorder is written directly
+func NewRecorder(logger log.Logger) *Recorder {
+	return &Recorder{logger: logger}
+}
+
+func (r *Recorder) Setup(config map[string]interface{}) error {
+	return nil
+}
+
+func (r *Recorder) Write(lm *log.LogMesg) {
+	e := Entry{
+		Level: lm.Level,
+		Time:  lm.Time,
+		Name:  lm.Name,
+		Mesg:  lm.Mesg,
+		Stack: lm.Stack,
+	}
+	if len(lm.Fields) > 0 {
+		e.Fields = make(map[string]interface{}, len(lm.Fields))
+		for _, field := range lm.Fields {
+			e.Fields[field.Key] = field.Value
+		}
+	}
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+}
+
+//WantStack is true to record the stacks of errors
+func (r *Recorder) WantStack() bool {
+	return true
+}
+
+func (r *Recorder) Rotate() {
+}
+
+func (r *Recorder) Flush() error {
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	return nil
+}
+
+//Entries returns the entries recorded after flushing the logger
+func (r *Recorder) Entries() []Entry {
+	if r.logger != nil {
+		//the entries are recorded already if it is closed
+		r.logger.Flush()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+//Reset forgets the entries recorded
+func (r *Recorder) Reset() {
+	if r.logger != nil {
+		r.logger.Flush()
+	}
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+//Find returns the entries of level whose message contains substr
+func (r *Recorder) Find(level int, substr string) []Entry {
+	var found []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level && strings.Contains(e.Mesg, substr) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+//AssertLogged reports an error unless a message of level containing
+//substr is recorded, returns the first one
+func (r *Recorder) AssertLogged(t testing.TB, level int, substr string) Entry {
+	t.Helper()
+	found := r.Find(level, substr)
+	if len(found) == 0 {
+		t.Errorf("no %s message contains %q, recorded:%s", log.LevelName(level), substr, r.dump())
+		return Entry{}
+	}
+	return found[0]
+}
+
+//AssertNotLogged reports an error if a message of level containing substr is recorded
+func (r *Recorder) AssertNotLogged(t testing.TB, level int, substr string) {
+	t.Helper()
+	if found := r.Find(level, substr); len(found) > 0 {
+		t.Errorf("%s message contains %q: %s", log.LevelName(level), substr, found[0])
+	}
+}
+
+//dump returns the entries one per line for the failure message
+func (r *Recorder) dump() string {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return " none"
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString("\n\t")
+		b.WriteString(e.String())
+	}
+	return b.String()
+}
+
+//tbHandler writes the messages to t.Log, so they are shown with
+//the test which fails, or with go test -v
+type tbHandler struct {
+	mu sync.Mutex
+	t  testing.TB //nil once the test finishes
+}
+
+//NewTBHandler returns a handler writing to t.Log, which is disabled when
+//the test finishes, since t.Log must not be called after it
+func NewTBHandler(t testing.TB) log.Handler {
+	h := &tbHandler{t: t}
+	t.Cleanup(h.detach)
+	return h
+}
+
+//detach stops writing to t
+func (h *tbHandler) detach() {
+	h.mu.Lock()
+	h.t = nil
+	h.mu.Unlock()
+}
+
+func (h *tbHandler) Setup(config map[string]interface{}) error {
+	return nil
+}
+
+func (h *tbHandler) Write(lm *log.LogMesg) {
+	var b strings.Builder
+	b.WriteString(Entry{Level: lm.Level, Name: lm.Name, Mesg: lm.Mesg}.String())
+	for _, field := range lm.Fields {
+		fmt.Fprintf(&b, " %s=%v", field.Key, field.Value)
+	}
+	if lm.Stack != "" {
+		b.WriteString("\n")
+		b.WriteString(lm.Stack)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.t != nil {
+		h.t.Log(b.String())
+	}
+}
+
+func (h *tbHandler) Rotate() {
+	//do nothing
+}
+
+func (h *tbHandler) Flush() error {
+	return nil
+}
+
+func (h *tbHandler) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package logtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Hacky-DH/goLib/log"
+)
+
+//fakeT records the failures and logs instead of reporting them
+type fakeT struct {
+	testing.TB
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, format)
+}
+
+func (t *fakeT) Log(args ...interface{}) {
+	t.logs = append(t.logs, args[0].(string))
+}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *fakeT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	logger, rec := New(t)
+	logger.Named("db").Warn("slow query %dms", 120, "table", "user")
+	logger.Error("query failed", "err", errors.New("timeout"))
+
+	e := rec.AssertLogged(t, log.WarnLevel, "slow query")
+	if e.Name != "db" || e.Mesg != "slow query 120ms" || e.Fields["table"] != "user" {
+		t.Errorf("entry is %+v", e)
+	}
+	if e := rec.AssertLogged(t, log.ErrorLevel, "failed"); e.Stack == "" {
+		t.Error("entry of error has no stack")
+	}
+	rec.AssertNotLogged(t, log.InfoLevel, "slow query")
+
+	ft := new(fakeT)
+	rec.AssertLogged(ft, log.InfoLevel, "slow query")
+	rec.AssertNotLogged(ft, log.WarnLevel, "slow")
+	if len(ft.errors) != 2 {
+		t.Error("failures expect 2 but is", ft.errors)
+	}
+
+	rec.Reset()
+	if entries := rec.Entries(); len(entries) != 0 {
+		t.Error("entries after reset:", entries)
+	}
+}
+
+func TestNewFlushesToTB(t *testing.T) {
+	ft := new(fakeT)
+	logger, _ := New(ft)
+	logger.Info("last words")
+	//the close of the logger flushes the message before t.Log is detached
+	ft.finish()
+	logger.Info("after the test")
+	if strings.Join(ft.logs, "|") != "[INFO] last words" {
+		t.Error("logs are", ft.logs)
+	}
+}
+
+func TestTBHandler(t *testing.T) {
+	ft := new(fakeT)
+	h := NewTBHandler(ft)
+	h.Write(&log.LogMesg{Level: log.InfoLevel, Name: "db", Mesg: "hi", Fields: []log.Field{{Key: "n", Value: 1}}})
+	ft.finish()
+	h.Write(&log.LogMesg{Level: log.InfoLevel, Mesg: "after the test"})
+	if strings.Join(ft.logs, "|") != "[INFO] [db] hi n=1" {
+		t.Error("logs are", ft.logs)
+	}
+}
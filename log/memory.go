@@ -0,0 +1,255 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//MemoryHandler keeps the recent messages of any level in a ring for
+//post-mortem context, they are served by ServeHTTP, and dumped to a file
+//by DumpToFile or when a message reaches the dump level
+type MemoryHandler struct {
+	handlerLevel
+	encoder      encoder
+	stack        bool
+	dumpPath     string
+	dumpLevel    int
+	dumpInterval time.Duration
+
+	mu       sync.Mutex //guard the fields below
+	ring     []*LogMesg
+	next     int //index of the next message in ring
+	full     bool
+	lastDump time.Time
+	dumpErr  error //of the last dump triggered
+
+	dumping sync.WaitGroup
+}
+
+func newmemoryHandler() Handler {
+	return new(MemoryHandler)
+}
+
+//NewMemoryHandler returns a memory handler to add by AddHandler
+func NewMemoryHandler(c MemoryConfig) (*MemoryHandler, error) {
+	h := new(MemoryHandler)
+	if err := h.setup(c); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *MemoryHandler) Setup(config map[string]interface{}) error {
+	c := DefaultMemoryConfig()
+	if err := DecodeConfig(config, &c); err != nil {
+		return err
+	}
+	return h.setup(c)
+}
+
+func (h *MemoryHandler) setup(c MemoryConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	h.SetLevel(c.Level)
+	h.encoder, _ = newEncoder(c.Format, encodeOptions{caller: c.Caller, stack: c.Stack})
+	h.stack = c.Stack
+	h.dumpPath = c.DumpPath
+	h.dumpLevel = c.DumpLevel
+	h.dumpInterval = c.DumpInterval
+	h.ring = make([]*LogMesg, c.Size)
+	return nil
+}
+
+//Write keeps lm, and dumps the ring in background if lm reaches the dump
+//level, FATAL and PANIC always dump, the others at most once a dumpInterval
+func (h *MemoryHandler) Write(lm *LogMesg) {
+	if !h.enabled(lm.Level) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ring[h.next] = lm
+	h.next++
+	if h.next == len(h.ring) {
+		h.next = 0
+		h.full = true
+	}
+	if h.dumpPath == "" || lm.Level < h.dumpLevel {
+		return
+	}
+	now := time.Now()
+	if lm.Level < FatalLevel && !h.lastDump.IsZero() && now.Sub(h.lastDump) < h.dumpInterval {
+		return
+	}
+	h.lastDump = now
+	mesgs := h.messages()
+	h.dumping.Add(1)
+	go func() {
+		defer h.dumping.Done()
+		path, err := h.dumpFile(lm.Time)
+		if err == nil {
+			err = h.dump(path, mesgs)
+		}
+		if err != nil {
+			h.mu.Lock()
+			h.dumpErr = err
+			h.mu.Unlock()
+		}
+	}()
+}
+
+//messages returns the ring, the oldest first, caller must hold h.mu
+func (h *MemoryHandler) messages() []*LogMesg {
+	var mesgs []*LogMesg
+	if h.full {
+		mesgs = append(mesgs, h.ring[h.next:]...)
+	}
+	return append(mesgs, h.ring[:h.next]...)
+}
+
+//Messages returns the messages kept, the oldest first
+func (h *MemoryHandler) Messages() []*LogMesg {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.messages()
+}
+
+//dumpFile returns a new file name like dumpPath-20060102-150405.log,
+//a counter is added if the name is taken
+func (h *MemoryHandler) dumpFile(t time.Time) (string, error) {
+	name := h.dumpPath + "-" + t.Format("20060102-150405")
+	path := name + ".log"
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return path, f.Close()
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		path = name + "." + strconv.Itoa(i) + ".log"
+	}
+}
+
+//DumpToFile writes the messages kept to path, the oldest first
+func (h *MemoryHandler) DumpToFile(path string) error {
+	return h.dump(path, h.Messages())
+}
+
+func (h *MemoryHandler) dump(path string, mesgs []*LogMesg) error {
+	var buf bytes.Buffer
+	for _, lm := range mesgs {
+		h.encoder.encode(&buf, lm)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+//ServeHTTP writes the messages kept, the oldest first, GET accepts
+//level: the lowest level, default TRACE
+//since: a time like 2006-01-02T15:04:05Z07:00 or a duration before now like 5m
+//grep: a substring of the message
+//n: the number of the newest messages to write
+func (h *MemoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	f, err := parseMemoryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var buf bytes.Buffer
+	for _, lm := range f.filter(h.Messages()) {
+		h.encoder.encode(&buf, lm)
+		buf.WriteByte('\n')
+	}
+	if _, ok := h.encoder.(*jsonEncoder); ok {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(buf.Bytes())
+}
+
+//memoryFilter selects the messages served by ServeHTTP
+type memoryFilter struct {
+	level int
+	since time.Time
+	grep  string
+	n     int //0 means all
+}
+
+func parseMemoryFilter(r *http.Request) (*memoryFilter, error) {
+	q := r.URL.Query()
+	f := &memoryFilter{level: TraceLevel}
+	var err error
+	if s := q.Get("level"); s != "" {
+		if f.level, err = ParseLevel(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := q.Get("since"); s != "" {
+		if d, e := time.ParseDuration(s); e == nil {
+			f.since = time.Now().Add(-d)
+		} else if f.since, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, errors.New("since must be a time like 2006-01-02T15:04:05Z07:00 or a duration")
+		}
+	}
+	f.grep = q.Get("grep")
+	if s := q.Get("n"); s != "" {
+		if f.n, err = strconv.Atoi(s); err != nil || f.n < 0 {
+			return nil, errors.New("n must be a non-negative number")
+		}
+	}
+	return f, nil
+}
+
+func (f *memoryFilter) filter(mesgs []*LogMesg) []*LogMesg {
+	var selected []*LogMesg
+	for _, lm := range mesgs {
+		if lm.Level < f.level || lm.Time.Before(f.since) {
+			continue
+		}
+		if f.grep != "" && !strings.Contains(lm.Mesg, f.grep) {
+			continue
+		}
+		selected = append(selected, lm)
+	}
+	if f.n > 0 && len(selected) > f.n {
+		selected = selected[len(selected)-f.n:]
+	}
+	return selected
+}
+
+func (h *MemoryHandler) WantStack() bool {
+	return h.stack
+}
+
+func (h *MemoryHandler) Rotate() {
+	//do nothing
+}
+
+//Flush waits for the dumps in progress, returns the error of the last
+//dump failed if any
+func (h *MemoryHandler) Flush() error {
+	h.dumping.Wait()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := h.dumpErr
+	h.dumpErr = nil
+	return err
+}
+
+func (h *MemoryHandler) Close() error {
+	return h.Flush()
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryRing(t *testing.T) {
+	c := DefaultMemoryConfig()
+	c.Size = 3
+	h, err := NewMemoryHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mesg := range []string{"a", "b", "c", "d", "e"} {
+		h.Write(&LogMesg{Level: TraceLevel, Time: time.Now(), Mesg: mesg})
+	}
+	var got []string
+	for _, lm := range h.Messages() {
+		got = append(got, lm.Mesg)
+	}
+	if strings.Join(got, "") != "cde" {
+		t.Error("ring keeps", got)
+	}
+
+	c.Size = 0
+	if _, err := NewMemoryHandler(c); err == nil {
+		t.Error("size 0 expect error")
+	}
+}
+
+func TestMemoryDump(t *testing.T) {
+	codes := stubExit(t)
+	dir := t.TempDir()
+	logger := NewLogger().(*LoggerImp)
+	defer logger.Close(context.Background())
+	logger.SetLevel(TraceLevel)
+	err := logger.SetLogger("memory", map[string]interface{}{
+		"dumpPath":     filepath.Join(dir, "dump"),
+		"dumpInterval": "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := logger.Handler("memory").(*MemoryHandler); !ok {
+		t.Fatal("memory handler is not found")
+	}
+
+	logger.Debug("context")
+	logger.Error("first error")
+	//rate limited
+	logger.Error("second error")
+	logger.Flush()
+	dumps, _ := filepath.Glob(filepath.Join(dir, "dump-*.log"))
+	if len(dumps) != 1 {
+		t.Fatal("dumps expect 1 but is", dumps)
+	}
+	b, _ := os.ReadFile(dumps[0])
+	if s := string(b); !strings.Contains(s, "[DEBUG] context\n") || !strings.HasSuffix(s, "[ERROR] first error\n") {
+		t.Error("dump is", s)
+	}
+
+	//fatal always dumps
+	logger.Fatal("fatal")
+	if len(*codes) != 1 {
+		t.Fatal("fatal does not exit")
+	}
+	dumps, _ = filepath.Glob(filepath.Join(dir, "dump-*.log"))
+	if len(dumps) != 2 {
+		t.Fatal("dumps expect 2 but is", dumps)
+	}
+	for _, dump := range dumps {
+		if b, _ := os.ReadFile(dump); strings.HasSuffix(string(b), "[FATAL] fatal\n") {
+			return
+		}
+	}
+	t.Error("no dump of fatal")
+}
+
+func TestMemoryServeHTTP(t *testing.T) {
+	h, err := NewMemoryHandler(DefaultMemoryConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	h.Write(&LogMesg{Level: DebugLevel, Time: now.Add(-time.Hour), Mesg: "old timeout"})
+	h.Write(&LogMesg{Level: DebugLevel, Time: now, Mesg: "debug timeout"})
+	h.Write(&LogMesg{Level: WarnLevel, Time: now, Mesg: "warn timeout"})
+	h.Write(&LogMesg{Level: ErrorLevel, Time: now, Mesg: "error"})
+
+	cases := map[string][]string{
+		"":                  {"old timeout", "debug timeout", "warn timeout", "error"},
+		"?level=warn":       {"warn timeout", "error"},
+		"?since=1m":         {"debug timeout", "warn timeout", "error"},
+		"?grep=timeout&n=2": {"debug timeout", "warn timeout"},
+		"?level=info&n=1":   {"error"},
+	}
+	for query, expect := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		if len(lines) != len(expect) {
+			t.Errorf("%q: expect %v but is %q", query, expect, lines)
+			continue
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, "] "+expect[i]) {
+				t.Errorf("%q: line %d expect %s but is %s", query, i, expect[i], line)
+			}
+		}
+	}
+	for query, code := range map[string]int{"?level=loud": 400, "?since=yesterday": 400, "?n=-1": 400} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+		if w.Code != code {
+			t.Errorf("%q: code expect %d but is %d", query, code, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error("POST code is", w.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RequestIDHeader   = "X-Request-ID"
+	TraceparentHeader = "traceparent"
+	max_request_id    = 128 //a longer request ID of the client is replaced
+)
+
+//Middleware returns a http middleware which takes the request ID of the
+//X-Request-ID header or generates one, sets it to the response header,
+//stores it and the trace of the W3C traceparent header in the request
+//context, see ContextWithFields, and logs the start and the finish of
+//every request with the status and latency, the finish of a 5xx status
+//at ERROR level, a panic of next is logged with status 500 and panics again
+func Middleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			ctx := ContextWithRequestID(r.Context(), id)
+			if traceID, spanID, ok := parseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+				ctx = ContextWithTrace(ctx, traceID, spanID)
+			}
+			r = r.WithContext(ctx)
+
+			logger.InfoContext(ctx, "request started",
+				"method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec != nil {
+					sw.status = http.StatusInternalServerError
+				} else if sw.status == 0 {
+					sw.status = http.StatusOK
+				}
+				finish := logger.InfoContext
+				if sw.status >= http.StatusInternalServerError {
+					finish = logger.ErrorContext
+				}
+				finish(ctx, "request finished",
+					"method", r.Method, "path", r.URL.Path, "status", sw.status,
+					"bytes", sw.bytes, "latency", time.Since(start))
+				if rec != nil {
+					panic(rec)
+				}
+			}()
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+//validRequestID returns true if id is printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > max_request_id {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+//newRequestID returns 16 random bytes in hex
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+//parseTraceparent parses version-traceid-spanid-flags like
+//00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceparent(s string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	traceID, spanID = parts[1], parts[2]
+	if !isLowerHex(parts[0]) || !isLowerHex(parts[3]) || len(parts[3]) != 2 ||
+		len(traceID) != 32 || !isLowerHex(traceID) || strings.Count(traceID, "0") == 32 ||
+		len(spanID) != 16 || !isLowerHex(spanID) || strings.Count(spanID, "0") == 16 {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+//statusWriter records the status and the bytes written
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	//skip the informational ones like 103 Early Hints
+	if w.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+//Hijack lets a handler take over the connection like a websocket
+//upgrade, the status is logged as 101 if none is written
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+//Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
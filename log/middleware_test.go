@@ -0,0 +1,148 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	var inside *http.Request
+	handler := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inside = r
+		logger.InfoContext(r.Context(), "inside")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	logger.Flush()
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 32 || RequestIDFromContext(inside.Context()) != id {
+		t.Fatal("request id is", id)
+	}
+	if len(h.mesgs) != 3 {
+		t.Fatal("messages expect 3 but is", len(h.mesgs))
+	}
+	for i, mesg := range []string{"request started", "inside", "request finished"} {
+		if lm := h.mesgs[i]; lm.Mesg != mesg || fieldMap(lm)[RequestIDKey] != id {
+			t.Errorf("message %d is %s %v", i, lm.Mesg, lm.Fields)
+		}
+	}
+	m := fieldMap(h.last())
+	if m["status"] != 200 || m["bytes"] != 2 || m["path"] != "/users" || m["latency"].(time.Duration) <= 0 {
+		t.Error("finish fields are", m)
+	}
+
+	//propagated
+	h.mesgs = nil
+	r := httptest.NewRequest(http.MethodPost, "/fail", nil)
+	r.Header.Set(RequestIDHeader, "abc-1")
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	logger.Flush()
+	if id := w.Header().Get(RequestIDHeader); id != "abc-1" {
+		t.Error("request id is", id)
+	}
+	if traceID, spanID := TraceFromContext(inside.Context()); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7" {
+		t.Error("trace is", traceID, spanID)
+	}
+	if lm := h.last(); lm.Level != ErrorLevel || fieldMap(lm)["status"] != http.StatusBadGateway {
+		t.Errorf("finish is %s %v", LevelName(lm.Level), lm.Fields)
+	}
+
+	//a bad request id is replaced
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "bad id")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if id := w.Header().Get(RequestIDHeader); len(id) != 32 {
+		t.Error("request id is", id)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	handler := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	}))
+	func() {
+		defer func() {
+			if rec := recover(); rec != "boom" {
+				t.Error("panic expect boom but is", rec)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	logger.Flush()
+	lm := h.last()
+	if lm.Mesg != "request finished" || lm.Level != ErrorLevel || fieldMap(lm)["status"] != http.StatusInternalServerError {
+		t.Errorf("finish is %s %s %v", LevelName(lm.Level), lm.Mesg, lm.Fields)
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	logger, h := newRecordLogger(t, false)
+	hijacked := make(chan error, 1)
+	handler := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		hijacked <- err
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+		rw.Flush()
+	}))
+	served := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		close(served)
+	}))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := <-hijacked; err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatal("hijack error:", err, resp.StatusCode)
+	}
+	<-served
+	logger.Flush()
+	if lm := h.last(); fieldMap(lm)["status"] != http.StatusSwitchingProtocols {
+		t.Error("finish fields are", lm.Fields)
+	}
+
+	//the recorder can not be hijacked
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := <-hijacked; err != http.ErrNotSupported {
+		t.Error("hijack expect ErrNotSupported but is", err)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	cases := map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       false,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":       false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":       false,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":       false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":          false,
+		"": false,
+	}
+	for s, expect := range cases {
+		if _, _, ok := parseTraceparent(s); ok != expect {
+			t.Errorf("parse %q expect %v", s, expect)
+		}
+	}
+}
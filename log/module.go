@@ -0,0 +1,88 @@
+package log
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+//ParseModuleLevels parses module levels like "dns=debug,upload=warn"
+func ParseModuleLevels(spec string) (map[string]int, error) {
+	modules := make(map[string]int)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		module := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || module == "" {
+			return nil, errors.New("module level must be module=level: " + item)
+		}
+		level, err := ParseLevel(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		modules[module] = level
+	}
+	return modules, nil
+}
+
+//formatModuleLevels is the inverse of ParseModuleLevels
+func formatModuleLevels(modules map[string]int) string {
+	items := make([]string, 0, len(modules))
+	for module, level := range modules {
+		items = append(items, module+"="+strings.ToLower(LevelName(level)))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+//SetModuleLevels overrides the level of the loggers returned by Named,
+//spec is like "dns=debug,upload=warn", dns applies to dns and dns.client
+//unless dns.client is set too, an empty spec removes all the overrides
+func (l *LoggerImp) SetModuleLevels(spec string) error {
+	modules, err := ParseModuleLevels(spec)
+	if err != nil {
+		return err
+	}
+	l.setModules(modules)
+	return nil
+}
+
+func (l *LoggerImp) setModules(modules map[string]int) {
+	if len(modules) == 0 {
+		l.modules.Store(nil)
+		return
+	}
+	l.modules.Store(&modules)
+}
+
+//ModuleLevels returns the module levels like "dns=debug,upload=warn"
+func (l *LoggerImp) ModuleLevels() string {
+	modules := l.modules.Load()
+	if modules == nil {
+		return ""
+	}
+	return formatModuleLevels(*modules)
+}
+
+//moduleLevel returns the level of the longest module matching the name of l,
+//or the logger level if none matches
+func (l *LoggerImp) moduleLevel() int {
+	modules := l.modules.Load()
+	if modules == nil || l.name == "" {
+		return l.Level()
+	}
+	name := l.name
+	for {
+		if level, ok := (*modules)[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return l.Level()
+		}
+		name = name[:i]
+	}
+}
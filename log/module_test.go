@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseModuleLevels(t *testing.T) {
+	modules, err := ParseModuleLevels(" dns=debug, upload=WARN,,x.y=3 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 3 || modules["dns"] != DebugLevel ||
+		modules["upload"] != WarnLevel || modules["x.y"] != ErrorLevel {
+		t.Error("parse module levels error:", modules)
+	}
+	if s := formatModuleLevels(modules); s != "dns=debug,upload=warn,x.y=error" {
+		t.Error("format module levels error:", s)
+	}
+	for _, spec := range []string{"dns", "=debug", "dns=loud"} {
+		if _, err := ParseModuleLevels(spec); err == nil {
+			t.Error("parse module levels expect error:", spec)
+		}
+	}
+}
+
+func TestNamedLogger(t *testing.T) {
+	logger := NewLogger().(*LoggerImp)
+	name := filepath.Join(t.TempDir(), "named.log")
+	if err := logger.SetLogger("file", map[string]interface{}{"path": name}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	logger.SetLevel(InfoLevel)
+	if err := logger.SetModuleLevels("dns=debug,dns.server=error"); err != nil {
+		t.Fatal(err)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	dns := logger.Named("dns")
+	client := dns.Named("client").With("id", 1)
+	server := dns.Named("server")
+	upload := logger.Named("upload")
+	if n := runtime.NumGoroutine(); n != goroutines {
+		t.Error("named loggers expect no new goroutines but", n-goroutines)
+	}
+
+	logger.Debug("root debug")
+	client.Debug("client debug")
+	server.Warn("server warn")
+	server.Error("server error")
+	upload.Debug("upload debug")
+	upload.Info("upload info")
+	logger.Close(context.Background())
+
+	fb, _ := os.ReadFile(name)
+	lines := strings.Split(strings.TrimSpace(string(fb)), "\n")
+	expect := []string{
+		"[DEBUG] [dns.client] client debug id=1",
+		"[ERROR] [dns.server] server error",
+		"[INFO] [upload] upload info",
+	}
+	if len(lines) != len(expect) {
+		t.Fatal("named log error:", lines)
+	}
+	for i, e := range expect {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("line %d expect suffix %q but is %q", i, e, lines[i])
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	net_dial_timeout  = 5 * time.Second
+	net_write_timeout = 10 * time.Second
+)
+
+//netHandler streams log lines to a collector in batches,
+//lines are spooled to a local file while the collector is down
+//and the spool is replayed in order on reconnect,
+//a line may be sent twice if the connection breaks during replay,
+//a spool file which can not be read is moved aside to name.bad
+type netHandler struct {
+	handlerLevel
+	network       string
+	addr          string
+	encoder       encoder
+	stack         bool
+	batchSize     int           //default 100 lines
+	flushInterval time.Duration //default 1s
+	minBackoff    time.Duration //default 1s
+	maxBackoff    time.Duration //default 1m
+	spoolPath     string        //absolute path
+	maxSpoolSize  int64         //default 100MB, full spool is moved to a segment
+	isCompress    bool          //compress spool segments, default true
+
+	mu    sync.Mutex //guard batch and count
+	batch bytes.Buffer
+	count int
+	kick  chan struct{}
+	quit  chan struct{}
+	done  chan struct{}
+
+	smu       sync.Mutex //guard the fields below, held while sending
+	conn      net.Conn
+	backoff   time.Duration
+	retryAt   time.Time
+	spool     *os.File
+	spoolSize int64
+}
+
+func newnetHandler() Handler {
+	return new(netHandler)
+}
+
+//NewNetHandler returns a net handler to add by AddHandler
+func NewNetHandler(c NetConfig) (Handler, error) {
+	h := new(netHandler)
+	if err := h.setup(c); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *netHandler) Setup(config map[string]interface{}) error {
+	c := DefaultNetConfig()
+	if err := DecodeConfig(config, &c); err != nil {
+		return err
+	}
+	return h.setup(c)
+}
+
+func (h *netHandler) setup(c NetConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	h.SetLevel(c.Level)
+	h.encoder, _ = newEncoder(c.Format, encodeOptions{caller: c.Caller, stack: c.Stack})
+	h.stack = c.Stack
+	h.network = c.Network
+	h.addr = c.Addr
+	h.batchSize = c.BatchSize
+	h.flushInterval = c.FlushInterval
+	h.minBackoff = c.MinBackoff
+	h.maxBackoff = c.MaxBackoff
+	h.spoolPath, _ = filepath.Abs(c.SpoolPath)
+	h.maxSpoolSize = c.MaxSpoolSize
+	h.isCompress = c.IsCompress
+
+	h.kick = make(chan struct{}, 1)
+	h.quit = make(chan struct{})
+	h.done = make(chan struct{})
+	go h.loop()
+	return nil
+}
+
+func (h *netHandler) Write(lm *LogMesg) {
+	if !h.enabled(lm.Level) {
+		return
+	}
+	h.mu.Lock()
+	h.encoder.encode(&h.batch, lm)
+	h.batch.WriteByte('\n')
+	h.count++
+	full := h.count >= h.batchSize
+	h.mu.Unlock()
+	if full {
+		select {
+		case h.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//loop sends batches in background so a slow collector never blocks logging
+func (h *netHandler) loop() {
+	defer close(h.done)
+	ticker := time.NewTicker(h.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.flush()
+		case <-h.kick:
+			h.flush()
+		case <-h.quit:
+			h.flush()
+			return
+		}
+	}
+}
+
+func (h *netHandler) flush() error {
+	h.mu.Lock()
+	data := append([]byte(nil), h.batch.Bytes()...)
+	h.batch.Reset()
+	h.count = 0
+	h.mu.Unlock()
+
+	h.smu.Lock()
+	defer h.smu.Unlock()
+	if h.connect() {
+		if err := h.replay(); err == nil {
+			if len(data) == 0 {
+				return nil
+			}
+			if err := h.write(data); err == nil {
+				return nil
+			}
+			h.disconnect()
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return h.spoolWrite(data)
+}
+
+//connect returns true if connected, dials with exponential backoff
+func (h *netHandler) connect() bool {
+	if h.conn != nil {
+		return true
+	}
+	now := time.Now()
+	if now.Before(h.retryAt) {
+		return false
+	}
+	conn, err := net.DialTimeout(h.network, h.addr, net_dial_timeout)
+	if err != nil {
+		if h.backoff == 0 {
+			h.backoff = h.minBackoff
+		} else if h.backoff *= 2; h.backoff > h.maxBackoff {
+			h.backoff = h.maxBackoff
+		}
+		h.retryAt = now.Add(h.backoff)
+		return false
+	}
+	h.conn = conn
+	h.backoff = 0
+	return true
+}
+
+//write sends p, a stuck collector times out like a broken one
+func (h *netHandler) write(p []byte) error {
+	h.conn.SetWriteDeadline(time.Now().Add(net_write_timeout))
+	_, err := h.conn.Write(p)
+	return err
+}
+
+func (h *netHandler) disconnect() {
+	if h.conn != nil {
+		h.conn.Close()
+		h.conn = nil
+	}
+	h.retryAt = time.Now().Add(h.minBackoff)
+	h.backoff = h.minBackoff
+}
+
+//spoolSegments returns the full spool segments in order
+func (h *netHandler) spoolSegments() ([]string, error) {
+	dir, base := filepath.Split(h.spoolPath)
+	files, err := Glob(dir, base+"-*", time.Now().Add(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	segments := files[:0]
+	for _, file := range files {
+		if strings.HasSuffix(file, ".gz") || strings.HasSuffix(file, ".log") {
+			segments = append(segments, file)
+		}
+	}
+	//the suffix is a fixed width time, so the name order is the time order
+	sort.Strings(segments)
+	return segments, nil
+}
+
+//replay sends the spooled lines, the oldest segment first
+func (h *netHandler) replay() error {
+	segments, err := h.spoolSegments()
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if err := h.sendFile(segment); err != nil {
+			return err
+		}
+	}
+	if h.spool != nil {
+		h.spool.Close()
+		h.spool = nil
+		h.spoolSize = 0
+	}
+	//the spool may be left by a previous process
+	if _, exist := FileExists(h.spoolPath); exist {
+		return h.sendFile(h.spoolPath)
+	}
+	return nil
+}
+
+//sendFile sends and removes a spool file, a file which can not be read
+//is moved aside, only a connection error stops the replay
+func (h *netHandler) sendFile(name string) error {
+	rerr, werr := h.sendFrom(name)
+	if werr != nil {
+		h.disconnect()
+		return werr
+	}
+	if rerr != nil {
+		if os.IsNotExist(rerr) {
+			return nil
+		}
+		return os.Rename(name, name+".bad")
+	}
+	return os.Remove(name)
+}
+
+//sendFrom copies the lines of a spool file to the connection,
+//returns the read error and the write error apart
+func (h *netHandler) sendFrom(name string) (rerr, werr error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return err, nil
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err, nil
+		}
+		defer gz.Close()
+		r = gz
+	}
+	buf := make([]byte, 32*1024)
+	last := byte('\n')
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if werr := h.write(buf[:n]); werr != nil {
+				return nil, werr
+			}
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			//end the partial line of a broken file
+			if last != '\n' {
+				if werr := h.write([]byte{'\n'}); werr != nil {
+					return nil, werr
+				}
+			}
+			return err, nil
+		}
+	}
+}
+
+//spoolWrite appends data to the spool,
+//a full spool is renamed or compressed to a segment
+func (h *netHandler) spoolWrite(data []byte) error {
+	if h.spool == nil {
+		f, err := os.OpenFile(h.spoolPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		h.spool = f
+		h.spoolSize = info.Size()
+	}
+	n, err := h.spool.Write(data)
+	h.spoolSize += int64(n)
+	if err != nil {
+		return err
+	}
+	if h.spoolSize < h.maxSpoolSize {
+		return nil
+	}
+	h.spool.Close()
+	h.spool = nil
+	h.spoolSize = 0
+	//the segment is renamed first and compressed to a temp file,
+	//so replay never reads a half written one
+	segment := h.spoolPath + "-" + time.Now().Format("20060102-150405.000000000") + ".log"
+	if err := os.Rename(h.spoolPath, segment); err != nil {
+		return err
+	}
+	if h.isCompress {
+		return compressFile(segment, gzipCompressor{}, gzip.DefaultCompression)
+	}
+	return nil
+}
+
+func (h *netHandler) WantStack() bool {
+	return h.stack
+}
+
+func (h *netHandler) Rotate() {
+	//do nothing
+}
+
+func (h *netHandler) Flush() error {
+	return h.flush()
+}
+
+func (h *netHandler) Close() error {
+	if h.quit == nil {
+		return nil
+	}
+	close(h.quit)
+	<-h.done
+	h.quit = nil
+
+	h.smu.Lock()
+	defer h.smu.Unlock()
+	var err error
+	if h.conn != nil {
+		err = h.conn.Close()
+		h.conn = nil
+	}
+	if h.spool != nil {
+		if serr := h.spool.Close(); err == nil {
+			err = serr
+		}
+		h.spool = nil
+	}
+	return err
+}
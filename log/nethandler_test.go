@@ -0,0 +1,182 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//collect accepts connections on ln and sends the received lines to lines
+func collect(ln net.Listener, lines chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+	}
+}
+
+func expectLines(t *testing.T, lines <-chan string, expect []string) {
+	t.Helper()
+	for _, e := range expect {
+		select {
+		case line := <-lines:
+			if !strings.HasSuffix(line, e) {
+				t.Fatalf("collector line expect suffix %q but is %q", e, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("collector line %q is not received", e)
+		}
+	}
+}
+
+func TestNetHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 16)
+	go collect(ln, lines)
+
+	logger := NewLogger()
+	config := make(map[string]interface{})
+	config["addr"] = ln.Addr().String()
+	config["spoolPath"] = filepath.Join(t.TempDir(), "spool")
+	config["batchSize"] = 2
+	if err := logger.SetLogger("net", config); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	logger.Info("info 0")
+	logger.Info("info 1")
+	//a full batch is sent without waiting for the flush interval
+	expectLines(t, lines, []string{"[INFO] info 0", "[INFO] info 1"})
+	logger.Warn("warn")
+	logger.Close(context.Background())
+	expectLines(t, lines, []string{"[WARN] warn"})
+}
+
+func TestNetHandlerBadSegment(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 64)
+	go collect(ln, lines)
+
+	//a segment truncated by a crash in the middle of compressing
+	spool := filepath.Join(t.TempDir(), "spool")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(gz, "lost line %d\n", i)
+	}
+	gz.Close()
+	bad := spool + "-20200101-000000.000000000.gz"
+	if err := os.WriteFile(bad, buf.Bytes()[:buf.Len()/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(spool+"-20200101-000001.000000000.log", []byte("good line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newnetHandler().(*netHandler)
+	config := map[string]interface{}{"addr": ln.Addr().String(), "spoolPath": spool}
+	if err := h.Setup(config); err != nil {
+		t.Fatal("setup net logger error:", err)
+	}
+	defer h.Close()
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "live"})
+	if err := h.Flush(); err != nil {
+		t.Fatal("flush error:", err)
+	}
+	//the lines read before the broken part may be sent
+	var received []string
+	for len(received) == 0 || !strings.HasSuffix(received[len(received)-1], "live") {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(2 * time.Second):
+			t.Fatal("live line is not received, lines are", received)
+		}
+	}
+	if len(received) < 2 || received[len(received)-2] != "good line" {
+		t.Error("good segment is not replayed, lines are", received)
+	}
+	if _, exist := FileExists(bad + ".bad"); !exist {
+		t.Error("bad segment is not moved aside")
+	}
+	if segments, _ := h.spoolSegments(); len(segments) != 0 {
+		t.Error("replayed segments expect removed but is", segments)
+	}
+}
+
+func TestNetHandlerSpool(t *testing.T) {
+	//reserve a port with no collector
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	h := newnetHandler().(*netHandler)
+	config := make(map[string]interface{})
+	config["addr"] = addr
+	config["spoolPath"] = filepath.Join(t.TempDir(), "spool")
+	config["minBackoff"] = 10 * time.Millisecond
+	config["maxBackoff"] = 20 * time.Millisecond
+	config["maxSpoolSize"] = int64(64)
+	if err := h.Setup(config); err != nil {
+		t.Fatal("setup net logger error:", err)
+	}
+	defer h.Close()
+
+	var expect []string
+	for i := 0; i < 10; i++ {
+		mesg := fmt.Sprintf("spooled %d", i)
+		expect = append(expect, mesg)
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: mesg})
+		if err := h.Flush(); err != nil {
+			t.Fatal("spool error:", err)
+		}
+	}
+	segments, err := h.spoolSegments()
+	if err != nil || len(segments) == 0 {
+		t.Fatal("full spool expect compressed segments but is", segments, err)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("can not listen on the reserved port again:", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 16)
+	go collect(ln, lines)
+
+	time.Sleep(30 * time.Millisecond)
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "live"})
+	if err := h.Flush(); err != nil {
+		t.Fatal("flush error:", err)
+	}
+	expectLines(t, lines, append(expect, "live"))
+	if segments, _ := h.spoolSegments(); len(segments) != 0 {
+		t.Error("replayed segments expect removed but is", segments)
+	}
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"errors"
+	"time"
+)
+
+//OverflowPolicy decides what a write does when the log buffer is full
+type OverflowPolicy int
+
+const (
+	//OverflowBlock waits until the buffer has room, the default
+	OverflowBlock OverflowPolicy = iota
+	//OverflowDropNewest discards the message being written
+	OverflowDropNewest
+	//OverflowDropOldest discards the oldest queued message to make room
+	OverflowDropOldest
+	//OverflowSync writes the message to the handlers in the calling goroutine,
+	//so it may be written before older queued messages
+	OverflowSync
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowSync:
+		return "sync"
+	default:
+		return "unknown"
+	}
+}
+
+//ParseOverflowPolicy parses the name returned by String
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for p := OverflowBlock; p <= OverflowSync; p++ {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return OverflowBlock, errors.New("Unknown overflow policy " + name)
+}
+
+func (p OverflowPolicy) drops() bool {
+	return p == OverflowDropNewest || p == OverflowDropOldest
+}
+
+//Option configures a logger created by NewLogger
+type Option func(*loggerCore)
+
+//WithBufferSize sets the number of messages the buffer holds
+func WithBufferSize(size int) Option {
+	return func(c *loggerCore) {
+		if size >= 0 {
+			c.bufferSize = size
+		}
+	}
+}
+
+//WithOverflowPolicy sets what a write does when the buffer is full
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(c *loggerCore) {
+		c.overflow = policy
+	}
+}
+
+//WithDropReportInterval sets how often the number of dropped messages
+//is written as a WARN message, and the number of messages suppressed
+//by sampling, 0 disables the report, default 10s
+func WithDropReportInterval(interval time.Duration) Option {
+	return func(c *loggerCore) {
+		c.reportInterval = interval
+	}
+}
+
+//Dropped returns the number of messages dropped as the buffer was full
+func (l *LoggerImp) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+//send queues lm according to the overflow policy,
+//returns false if lm is not queued,
+//FATAL and PANIC messages are never dropped
+func (l *LoggerImp) send(lm *LogMesg) bool {
+	if l.overflow == OverflowBlock || lm.Level >= FatalLevel {
+		l.mesgs <- lm
+		return true
+	}
+	select {
+	case l.mesgs <- lm:
+		return true
+	default:
+	}
+	switch l.overflow {
+	case OverflowDropNewest:
+		l.dropped.Add(1)
+		return false
+	case OverflowDropOldest:
+		for {
+			select {
+			case old := <-l.mesgs:
+				if old.Level >= FatalLevel {
+					//queued by another goroutine, never dropped but queued again
+					l.mesgs <- old
+				} else {
+					l.dropped.Add(1)
+				}
+			default:
+			}
+			select {
+			case l.mesgs <- lm:
+				return true
+			default:
+			}
+		}
+	}
+	return false
+}
+
+//reportDropped writes the number of messages dropped since the last report
+func (l *LoggerImp) reportDropped(reported *uint64) {
+	dropped := l.dropped.Load()
+	if dropped == *reported {
+		return
+	}
+	n := dropped - *reported
+	*reported = dropped
+	l.dispatch(&LogMesg{
+		Level: WarnLevel,
+		Time:  time.Now(),
+		Mesg:  "[log buffer] dropped message(s) as the buffer is full",
+		Fields: []Field{
+			{Key: "dropped", Value: n},
+			{Key: "total", Value: dropped},
+			{Key: "policy", Value: l.overflow.String()},
+		},
+	})
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//blockHandler records messages and blocks writing the message "block"
+//until release is closed
+type blockHandler struct {
+	mu      sync.Mutex
+	mesgs   []string
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func newBlockHandler() *blockHandler {
+	return &blockHandler{
+		blocked: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (h *blockHandler) Setup(config map[string]interface{}) error { return nil }
+func (h *blockHandler) Rotate()                                   {}
+func (h *blockHandler) Flush() error                              { return nil }
+func (h *blockHandler) Close() error                              { return nil }
+
+func (h *blockHandler) Write(lm *LogMesg) {
+	if lm.Mesg == "block" {
+		close(h.blocked)
+		<-h.release
+	}
+	h.mu.Lock()
+	h.mesgs = append(h.mesgs, lm.Mesg)
+	h.mu.Unlock()
+}
+
+func (h *blockHandler) written() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.mesgs...)
+}
+
+func newBlockedLogger(t *testing.T, opts ...Option) (*LoggerImp, *blockHandler) {
+	logger := NewLogger(opts...).(*LoggerImp)
+	h := newBlockHandler()
+	logger.addHandler("block", h)
+	logger.Info("block")
+	select {
+	case <-h.blocked:
+	case <-time.After(time.Second):
+		t.Fatal("handler is not blocked")
+	}
+	return logger, h
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	logger, h := newBlockedLogger(t, WithBufferSize(2),
+		WithOverflowPolicy(OverflowDropNewest), WithDropReportInterval(0))
+	for i := 0; i < 10; i++ {
+		logger.Info("info %d", i)
+	}
+	if n := logger.Dropped(); n != 8 {
+		t.Error("dropped expect = 8 but is", n)
+	}
+	close(h.release)
+	logger.Close(context.Background())
+
+	mesgs := h.written()
+	if len(mesgs) != 4 || mesgs[1] != "info 0" || mesgs[2] != "info 1" {
+		t.Fatal("written messages error:", mesgs)
+	}
+	if !strings.Contains(mesgs[3], "dropped") {
+		t.Error("dropped report expect at close but is", mesgs[3])
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	logger, h := newBlockedLogger(t, WithBufferSize(2),
+		WithOverflowPolicy(OverflowDropOldest), WithDropReportInterval(10*time.Millisecond))
+	for i := 0; i < 10; i++ {
+		logger.Info("info %d", i)
+	}
+	if n := logger.Dropped(); n != 8 {
+		t.Error("dropped expect = 8 but is", n)
+	}
+	close(h.release)
+	time.Sleep(50 * time.Millisecond)
+	logger.Flush()
+
+	mesgs := h.written()
+	if len(mesgs) != 4 || mesgs[1] != "info 8" || mesgs[2] != "info 9" {
+		t.Fatal("written messages error:", mesgs)
+	}
+	if !strings.Contains(mesgs[3], "dropped") {
+		t.Error("periodic dropped report expect but is", mesgs[3])
+	}
+	logger.Close(context.Background())
+	if n := len(h.written()); n != 4 {
+		t.Error("no new drops expect no more report but written", n)
+	}
+}
+
+func TestOverflowDropOldestKeepsFatal(t *testing.T) {
+	logger, h := newBlockedLogger(t, WithBufferSize(2),
+		WithOverflowPolicy(OverflowDropOldest), WithDropReportInterval(0))
+	//as Fatal queues it, without exiting
+	logger.send(&LogMesg{Level: FatalLevel, Time: time.Now(), Mesg: "fatal"})
+	for i := 0; i < 3; i++ {
+		logger.Info("info %d", i)
+	}
+	if n := logger.Dropped(); n != 2 {
+		t.Error("dropped expect = 2 but is", n)
+	}
+	close(h.release)
+	logger.Close(context.Background())
+
+	mesgs := h.written()
+	if len(mesgs) < 3 || mesgs[1] != "fatal" || mesgs[2] != "info 2" {
+		t.Fatal("written messages error:", mesgs)
+	}
+}
+
+func TestOverflowSync(t *testing.T) {
+	logger, h := newBlockedLogger(t, WithBufferSize(1), WithOverflowPolicy(OverflowSync))
+	for i := 0; i < 3; i++ {
+		logger.Info("info %d", i)
+	}
+	//info 0 is queued, the others are written synchronously
+	if mesgs := h.written(); len(mesgs) != 2 || mesgs[0] != "info 1" || mesgs[1] != "info 2" {
+		t.Fatal("sync written messages error:", mesgs)
+	}
+	close(h.release)
+	logger.Close(context.Background())
+	if mesgs := h.written(); len(mesgs) != 4 || logger.Dropped() != 0 {
+		t.Error("written messages error:", mesgs)
+	}
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]func() Handler)
+)
+
+func init() {
+	RegisterHandler("console", newconsoleHandler)
+	RegisterHandler("file", newfileHandler)
+	RegisterHandler("syslog", newsyslogHandler)
+	RegisterHandler("net", newnetHandler)
+	RegisterHandler("memory", newmemoryHandler)
+}
+
+//RegisterHandler makes a handler type available to SetLogger by name,
+//it panics if factory is nil or the name is registered twice
+func RegisterHandler(name string, factory func() Handler) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if factory == nil {
+		panic("log: RegisterHandler factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("log: RegisterHandler called twice for handler " + name)
+	}
+	registry[name] = factory
+}
+
+//newHandler returns a new handler of the registered type
+func newHandler(handlerType string) (Handler, error) {
+	registryLock.RLock()
+	factory, ok := registry[handlerType]
+	registryLock.RUnlock()
+	if !ok {
+		return nil, errors.New("Unknown log handler.")
+	}
+	return factory(), nil
+}
+
+//namedHandler is a handler instance added to a logger
+type namedHandler struct {
+	name    string
+	handler Handler
+	sampled bool //skip the messages suppressed by sampling
+}
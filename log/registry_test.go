@@ -0,0 +1,112 @@
+package log
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//captureHandler is a third party handler recording messages
+type captureHandler struct {
+	mu     sync.Mutex
+	prefix string
+	mesgs  []string
+	closed bool
+}
+
+func (h *captureHandler) Setup(config map[string]interface{}) error {
+	if prefix, ok := config["prefix"]; ok {
+		h.prefix = prefix.(string)
+	}
+	return nil
+}
+
+func (h *captureHandler) Write(lm *LogMesg) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mesgs = append(h.mesgs, h.prefix+lm.Mesg)
+}
+
+func (h *captureHandler) Rotate()      {}
+func (h *captureHandler) Flush() error { return nil }
+func (h *captureHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	return nil
+}
+
+var captures []*captureHandler
+
+func init() {
+	RegisterHandler("capture", func() Handler {
+		h := new(captureHandler)
+		captures = append(captures, h)
+		return h
+	})
+}
+
+func TestRegisterHandler(t *testing.T) {
+	captures = nil
+	logger := NewLogger()
+	if err := logger.SetLogger("capture", map[string]interface{}{"prefix": "a:"}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	//same name replaces and closes the old instance
+	if err := logger.SetLogger("capture", map[string]interface{}{"prefix": "b:"}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	if err := logger.SetLogger("capture", map[string]interface{}{"name": "other", "prefix": "c:"}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	if err := logger.SetLogger("nope", nil); err == nil {
+		t.Error("unknown handler expect error")
+	}
+	logger.Info("info")
+	logger.Close(context.Background())
+
+	if len(captures) != 3 {
+		t.Fatal("handler instances expect = 3 but is", len(captures))
+	}
+	if len(captures[0].mesgs) != 0 || !captures[0].closed {
+		t.Error("replaced handler expect closed and unused", captures[0].mesgs)
+	}
+	if strings.Join(captures[1].mesgs, ",") != "b:info" ||
+		strings.Join(captures[2].mesgs, ",") != "c:info" {
+		t.Error("handlers content error:", captures[1].mesgs, captures[2].mesgs)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("register twice expect panic")
+		}
+	}()
+	RegisterHandler("capture", func() Handler { return new(captureHandler) })
+}
+
+func TestMultiFileHandlers(t *testing.T) {
+	logger := NewLogger()
+	names := []string{"test_multi_a.log", "test_multi_b.log"}
+	for _, name := range names {
+		defer os.Remove(name)
+		config := make(map[string]interface{})
+		config["name"] = name
+		config["path"] = name
+		if err := logger.SetLogger("file", config); err != nil {
+			t.Fatal("set logger error:", err)
+		}
+	}
+	logger.Info("info")
+	logger.Close(context.Background())
+	for _, name := range names {
+		fb, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(fb), "[INFO] info\n") {
+			t.Error(name, "content error:", string(fb))
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package log
+
+//Reopener is implemented by handlers writing to a file which may be
+//moved by an external tool like logrotate
+type Reopener interface {
+	Reopen() error
+}
+
+//Reopen reopens the files of all handlers implementing Reopener,
+//e.g. in the postrotate script of logrotate, see HandleReopenSignal
+func (l *LoggerImp) Reopen() error {
+	l.hlock.RLock()
+	defer l.hlock.RUnlock()
+	var first error
+	for _, output := range l.outputs {
+		if h, ok := output.handler.(Reopener); ok {
+			if err := h.Reopen(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
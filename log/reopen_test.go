@@ -0,0 +1,79 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newExternalLogger(t *testing.T, path string) *LoggerImp {
+	c := DefaultFileConfig()
+	c.Path = path
+	c.ExternalRotate = true
+	c.MaxFileSize = 10
+	h, err := NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewLogger().(*LoggerImp)
+	t.Cleanup(func() { logger.Close(context.Background()) })
+	logger.AddHandler("file", h)
+	return logger
+}
+
+func expectContent(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	fb, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(fb), "\n"), "\n")
+	if len(got) != len(lines) {
+		t.Fatalf("%s expect %v but is %q", filepath.Base(path), lines, got)
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(got[i], "] "+line) {
+			t.Errorf("%s line %d expect %s but is %q", filepath.Base(path), i, line, got[i])
+		}
+	}
+}
+
+func TestExternalRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	logger := newExternalLogger(t, path)
+
+	//move and create like logrotate create
+	logger.Info("a")
+	logger.Flush()
+	os.Rename(path, path+".1")
+	logger.Info("b")
+	logger.Flush()
+	os.WriteFile(path, nil, 0644)
+	logger.Info("c")
+	logger.Flush()
+	expectContent(t, path+".1", "a", "b")
+	expectContent(t, path, "c")
+
+	//move and reopen like logrotate nocreate and postrotate
+	os.Rename(path, path+".2")
+	if err := logger.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("d")
+	logger.Flush()
+	expectContent(t, path+".2", "c")
+	expectContent(t, path, "d")
+
+	//copytruncate
+	os.Truncate(path, 0)
+	logger.Info("e")
+	logger.Flush()
+	expectContent(t, path, "e")
+
+	if backups, _ := filepath.Glob(path + "-*"); len(backups) != 0 {
+		t.Error("external rotate file is rotated:", backups)
+	}
+}
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//a temp file of compressFile not modified for stale_temp_age is left by a crash
+const stale_temp_age = time.Hour
+
+//backup is a rotated file of a file handler
+type backup struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+//backupName matches the suffixes of backupSuffix
+var backupName = regexp.MustCompile(`^(\d{8}-\d{6}|\d{8}|\d{10}|\d{12}|\d{4}-W\d{2})(\.\d+)?$`)
+
+//IsBackupSuffix returns true if s is the suffix the file handler adds to
+//a rotated file, without the dash and the extension, a time like
+//20060102-150405 or a schedule name like 20060102, and an optional
+//counter like .1, e.g. 20060102-150405 of app.log-20060102-150405.gz
+func IsBackupSuffix(s string) bool {
+	return backupName.MatchString(s)
+}
+
+//isBackup returns true if name is a rotated file of the log file base,
+//which is named base-suffix.log or base-suffix plus the extension
+//of a compressor like .gz, temp files being compressed are not
+func isBackup(base, name string) bool {
+	if !strings.HasPrefix(name, base+"-") {
+		return false
+	}
+	suffix, ok := strings.CutSuffix(name[len(base)+1:], ".log")
+	if !ok {
+		if suffix, ok = trimCompressedExt(suffix); !ok {
+			return false
+		}
+	}
+	return IsBackupSuffix(suffix)
+}
+
+//backups returns the rotated files of h, the oldest first
+func (h *fileHandler) backups() ([]backup, error) {
+	dir, base := filepath.Split(h.fileName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backup
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackup(base, entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			//removed meanwhile
+			continue
+		}
+		backups = append(backups, backup{
+			path:    filepath.Join(dir, entry.Name()),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.Before(backups[j].modTime)
+		}
+		return backups[i].path < backups[j].path
+	})
+	return backups, nil
+}
+
+//expired returns the backups older than maxRollingTime and the oldest
+//ones beyond maxBackups or maxTotalSize, backups must be the oldest first
+func (h *fileHandler) expired(backups []backup, now time.Time) []backup {
+	keep := len(backups)
+	var total int64
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		total += b.size
+		if now.Sub(b.modTime) > h.maxRollingTime ||
+			(h.maxBackups > 0 && len(backups)-i > h.maxBackups) ||
+			(h.maxTotalSize > 0 && total > h.maxTotalSize) {
+			break
+		}
+		keep = i
+	}
+	return backups[:keep]
+}
+
+//delStaleTemps deletes the temp files of the compressions which crashed,
+//in dry run mode they are only logged
+func (h *fileHandler) delStaleTemps(now time.Time) {
+	dir, base := filepath.Split(h.fileName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base+"-") || !strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < stale_temp_age {
+			continue
+		}
+		if h.dryRun {
+			h.write(InfoLevel, "[rolling log] dry run, would delete temp file %s", name)
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err == nil {
+			h.write(DebugLevel, "delete temp file %s done", name)
+		}
+	}
+}
+
+//delOldFiles deletes the expired backups, the oldest first,
+//in dry run mode they are only logged, returns the expired files
+func (h *fileHandler) delOldFiles() ([]string, error) {
+	h.delStaleTemps(time.Now())
+	backups, err := h.backups()
+	if err != nil {
+		h.write(ErrorLevel, "[rolling log] find old log files of %s err %s", h.fileName, err)
+		return nil, err
+	}
+	expired := h.expired(backups, time.Now())
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	h.write(DebugLevel, "[rolling log] find %d old log file(s) of %s", len(expired), h.fileName)
+	files := make([]string, len(expired))
+	for i, b := range expired {
+		files[i] = b.path
+		if h.dryRun {
+			h.write(InfoLevel, "[rolling log] dry run, would delete log file %s", filepath.Base(b.path))
+			continue
+		}
+		if err := os.Remove(b.path); err == nil {
+			h.write(DebugLevel, "delete log file %s done", filepath.Base(b.path))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//writeBackup creates a file of size bytes modified age ago
+func writeBackup(t *testing.T, path string, size int, age time.Duration) {
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaleTemps(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "app.log-20200101-000000.gz.123.tmp")
+	fresh := filepath.Join(dir, "app.log-20200102-000000.gz.456.tmp")
+	other := filepath.Join(dir, "other.gz.789.tmp")
+	writeBackup(t, stale, 100, 2*stale_temp_age)
+	writeBackup(t, fresh, 100, 0)
+	writeBackup(t, other, 100, 2*stale_temp_age)
+
+	c := DefaultFileConfig()
+	c.Path = filepath.Join(dir, "app.log")
+	h, err := NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	h.(*fileHandler).delOldFiles()
+	if _, exist := FileExists(stale); exist {
+		t.Error("stale temp file is not deleted")
+	}
+	for _, path := range []string{fresh, other} {
+		if _, exist := FileExists(path); !exist {
+			t.Error("temp file is deleted:", path)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	//backups of app.log, the newest last
+	names := []string{
+		"app.log-20200101-000000.gz",
+		"app.log-20200102-000000.log",
+		"app.log-20200103-000000.gz",
+		"app.log-20200104-000000.gz",
+	}
+	for i, name := range names {
+		writeBackup(t, filepath.Join(dir, name), 100, time.Duration(len(names)-i)*time.Hour)
+	}
+	//not ours
+	for _, name := range []string{"other.gz", "app.log2-20200101-000000.gz", "app.log.lock"} {
+		writeBackup(t, filepath.Join(dir, name), 100, 100*time.Hour)
+	}
+
+	cases := []struct {
+		name    string
+		config  func(*FileConfig)
+		expired int
+	}{
+		{"age", func(c *FileConfig) { c.MaxRollingTime = 150 * time.Minute }, 2},
+		{"count", func(c *FileConfig) { c.MaxBackups = 3 }, 1},
+		{"size", func(c *FileConfig) { c.MaxTotalSize = 250 }, 2},
+		{"all", func(c *FileConfig) { c.MaxBackups = 3; c.MaxTotalSize = 150 }, 3},
+		{"none", func(c *FileConfig) {}, 0},
+	}
+	for _, cs := range cases {
+		c := DefaultFileConfig()
+		c.Path = filepath.Join(dir, "app.log")
+		c.DryRun = true
+		cs.config(&c)
+		h, err := NewFileHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Close()
+		files, err := h.(*fileHandler).delOldFiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expect []string
+		for _, name := range names[:cs.expired] {
+			expect = append(expect, filepath.Join(dir, name))
+		}
+		if !reflect.DeepEqual(files, expect) {
+			t.Errorf("%s: expired expect %v but is %v", cs.name, expect, files)
+		}
+	}
+	//dry run deletes nothing
+	if entries, _ := os.ReadDir(dir); len(entries) != len(names)+4 {
+		t.Error("dry run deletes files:", len(entries))
+	}
+
+	c := DefaultFileConfig()
+	c.Path = filepath.Join(dir, "app.log")
+	c.MaxBackups = 1
+	h, err := NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	h.(*fileHandler).delOldFiles()
+	for i, name := range names {
+		_, exist := FileExists(filepath.Join(dir, name))
+		if exist != (i == len(names)-1) {
+			t.Errorf("%s exist is %v", name, exist)
+		}
+	}
+	if _, exist := FileExists(filepath.Join(dir, "other.gz")); !exist {
+		t.Error("file not ours is deleted")
+	}
+}
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//SamplingRule limits the messages of one template per second,
+//the First messages pass, then every Thereafter-th one,
+//Thereafter 0 suppresses all the messages after the First ones
+type SamplingRule struct {
+	First      int `config:"first"`
+	Thereafter int `config:"thereafter"`
+}
+
+func (r *SamplingRule) Validate() error {
+	if r.First < 0 {
+		return &ConfigError{Key: "first", Value: r.First, Err: errors.New("must not be negative")}
+	}
+	if r.Thereafter < 0 {
+		return &ConfigError{Key: "thereafter", Value: r.Thereafter, Err: errors.New("must not be negative")}
+	}
+	return nil
+}
+
+//WithSampling samples the messages of level by rule, a template is the
+//format of a message and the name of its logger, the number of suppressed
+//messages of every template is reported at the drop report interval,
+//only the handlers switched on by SetSampled are sampled
+func WithSampling(level int, rule SamplingRule) Option {
+	return func(c *loggerCore) {
+		if c.sampler == nil {
+			c.sampler = &sampler{rules: make(map[int]SamplingRule), counts: make(map[sampleKey]*sampleCount)}
+		}
+		c.sampler.rules[level] = rule
+	}
+}
+
+type sampleKey struct {
+	level  int
+	name   string
+	format string
+}
+
+type sampleCount struct {
+	second     int64 //the second n is counted in
+	n          int
+	suppressed uint64 //since the last report
+}
+
+//sampler counts the messages of every template,
+//rules are not modified after the logger is created
+type sampler struct {
+	rules  map[int]SamplingRule
+	mu     sync.Mutex
+	counts map[sampleKey]*sampleCount
+}
+
+//sample returns false if the message should be suppressed
+func (s *sampler) sample(level int, name, format string, now time.Time) bool {
+	rule, ok := s.rules[level]
+	if !ok {
+		return true
+	}
+	key := sampleKey{level: level, name: name, format: format}
+	second := now.Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, ok := s.counts[key]
+	if !ok {
+		count = new(sampleCount)
+		s.counts[key] = count
+	}
+	if count.second != second {
+		count.second = second
+		count.n = 0
+	}
+	count.n++
+	if count.n <= rule.First {
+		return true
+	}
+	if rule.Thereafter > 0 && (count.n-rule.First)%rule.Thereafter == 0 {
+		return true
+	}
+	count.suppressed++
+	return false
+}
+
+type suppressed struct {
+	sampleKey
+	count uint64
+}
+
+//collect returns and resets the suppressed counts,
+//templates not seen since the last second are forgotten
+func (s *sampler) collect(now time.Time) []suppressed {
+	var list []suppressed
+	second := now.Unix()
+	s.mu.Lock()
+	for key, count := range s.counts {
+		if count.suppressed > 0 {
+			list = append(list, suppressed{key, count.suppressed})
+			count.suppressed = 0
+		} else if count.second < second-1 {
+			delete(s.counts, key)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].name != list[j].name {
+			return list[i].name < list[j].name
+		}
+		if list[i].format != list[j].format {
+			return list[i].format < list[j].format
+		}
+		return list[i].level < list[j].level
+	})
+	return list
+}
+
+//sample returns false if the message is suppressed for every handler,
+//a suppressed message is still written to the handlers not sampled
+func (l *LoggerImp) sample(level int, format string) (suppressed bool, ok bool) {
+	if l.sampler == nil || l.sampler.sample(level, l.name, format, time.Now()) {
+		return false, true
+	}
+	return true, !l.allSampled.Load()
+}
+
+//reportSuppressed writes the number of suppressed messages of every template
+//to the sampled handlers
+func (l *LoggerImp) reportSuppressed() {
+	if l.sampler == nil {
+		return
+	}
+	now := time.Now()
+	for _, s := range l.sampler.collect(now) {
+		l.dispatch(&LogMesg{
+			Level: s.level,
+			Time:  now,
+			Name:  s.name,
+			Mesg:  "[log sampling] suppressed " + strconv.FormatUint(s.count, 10) + " similar message(s)",
+			Fields: []Field{
+				{Key: "template", Value: s.format},
+			},
+			summary: true,
+		})
+	}
+}
+
+//SetSampled switches sampling on or off for the handler named name
+func (l *LoggerImp) SetSampled(name string, sampled bool) error {
+	l.hlock.Lock()
+	defer l.hlock.Unlock()
+	outputs := make([]namedHandler, len(l.outputs))
+	copy(outputs, l.outputs)
+	for i := range outputs {
+		if outputs[i].name == name {
+			outputs[i].sampled = sampled
+			l.setOutputs(outputs)
+			return nil
+		}
+	}
+	return errors.New("Unknown log handler " + name)
+}
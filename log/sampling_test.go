@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	s := &sampler{
+		rules:  map[int]SamplingRule{ErrorLevel: {First: 3, Thereafter: 5}},
+		counts: make(map[sampleKey]*sampleCount),
+	}
+	now := time.Unix(1000, 0)
+	passed := 0
+	for i := 0; i < 20; i++ {
+		if s.sample(ErrorLevel, "", "failed %d", now) {
+			passed++
+		}
+	}
+	//3 first and the 8th, 13th, 18th
+	if passed != 6 {
+		t.Error("passed messages expect 6 but is", passed)
+	}
+	if !s.sample(WarnLevel, "", "failed %d", now) {
+		t.Error("level without rule is sampled")
+	}
+	if !s.sample(ErrorLevel, "other", "failed %d", now) {
+		t.Error("template of another logger is sampled")
+	}
+	//a new second starts again
+	if !s.sample(ErrorLevel, "", "failed %d", now.Add(time.Second)) {
+		t.Error("first message of next second is sampled")
+	}
+
+	list := s.collect(now.Add(time.Second))
+	if len(list) != 1 || list[0].format != "failed %d" || list[0].count != 14 {
+		t.Errorf("suppressed error: %+v", list)
+	}
+	//the idle templates are forgotten
+	s.collect(now.Add(3 * time.Second))
+	if len(s.counts) != 0 {
+		t.Error("idle templates are not forgotten:", len(s.counts))
+	}
+}
+
+func TestSampling(t *testing.T) {
+	logger := NewLogger(WithSampling(ErrorLevel, SamplingRule{First: 2})).(*LoggerImp)
+	all, sampled := new(recordHandler), new(recordHandler)
+	logger.AddHandler("all", all)
+	logger.AddHandler("sampled", sampled)
+	if err := logger.SetSampled("sampled", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetSampled("none", true); err == nil {
+		t.Error("sampling unknown handler expect error")
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Error("failed %d", i)
+		logger.Info("info %d", i)
+	}
+	logger.Close(context.Background())
+
+	if len(all.mesgs) != 20 {
+		t.Error("not sampled handler messages expect 20 but is", len(all.mesgs))
+	}
+	var errs, infos int
+	var summary *LogMesg
+	for _, lm := range sampled.mesgs {
+		switch {
+		case lm.summary:
+			summary = lm
+		case lm.Level == ErrorLevel:
+			errs++
+		default:
+			infos++
+		}
+	}
+	if errs != 2 || infos != 10 {
+		t.Errorf("sampled handler errors expect 2 but is %d, infos expect 10 but is %d", errs, infos)
+	}
+	if summary == nil || summary.Level != ErrorLevel ||
+		summary.Mesg != "[log sampling] suppressed 8 similar message(s)" ||
+		summary.Fields[0].Value != "failed %d" {
+		t.Errorf("summary error: %+v", summary)
+	}
+}
+
+func TestSamplingAllHandlers(t *testing.T) {
+	logger := NewLogger(WithSampling(InfoLevel, SamplingRule{First: 1, Thereafter: 2})).(*LoggerImp)
+	defer logger.Close(context.Background())
+	if err := logger.SetLogger("capture", map[string]interface{}{"sampled": true}); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	if !logger.allSampled.Load() {
+		t.Fatal("all handlers expect sampled")
+	}
+	h := captures[len(captures)-1]
+	for i := 0; i < 5; i++ {
+		logger.Info("hi")
+	}
+	logger.Flush()
+	//the 1st, 3rd and 5th
+	if len(h.mesgs) != 3 {
+		t.Error("messages expect 3 but is", h.mesgs)
+	}
+	if err := logger.SetLogger("capture", map[string]interface{}{"sampled": "maybe"}); err == nil {
+		t.Error("bad sampled expect error")
+	}
+}
@@ -0,0 +1,232 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//schedule decides when a file is rotated at wall clock boundaries
+type schedule interface {
+	//next returns the first boundary after t
+	next(t time.Time) time.Time
+	//prev returns the last boundary at or before t, the start of the
+	//period t is in, the zero time if there is none
+	prev(t time.Time) time.Time
+	//name returns the name of the period starting at the boundary t
+	name(t time.Time) string
+}
+
+//parseSchedule parses hourly, daily, weekly or a cron expression
+func parseSchedule(s string) (schedule, error) {
+	switch s {
+	case "hourly", "daily", "weekly":
+		return calendarSchedule(s), nil
+	}
+	c, err := parseCron(s)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//calendarSchedule rotates hourly at :00, daily at local midnight
+//or weekly at local midnight of Monday
+type calendarSchedule string
+
+func (s calendarSchedule) next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch s {
+	case "hourly":
+		return time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+	case "daily":
+		return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+	default:
+		days := (8 - int(t.Weekday())) % 7
+		if days == 0 {
+			days = 7
+		}
+		return time.Date(y, m, d+days, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func (s calendarSchedule) prev(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch s {
+	case "hourly":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case "daily":
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	default:
+		days := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-days, 0, 0, 0, 0, t.Location())
+	}
+}
+
+//name is like 2006010215 for hourly, 20060102 for daily and 2006-W01 for weekly
+func (s calendarSchedule) name(t time.Time) string {
+	switch s {
+	case "hourly":
+		return t.Format("2006010215")
+	case "daily":
+		return t.Format("20060102")
+	default:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+}
+
+//cronSchedule is a cron expression of 5 fields, minute hour day-of-month
+//month day-of-week, a field is * or a list of numbers, ranges like 1-5
+//and steps like */15, 5/15 or 8-18/2, day-of-week 0 and 7 are Sunday
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 //bit n is set if n matches
+	domAny, dowAny                bool
+}
+
+//the bounds of the cron fields
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+//a schedule is never due after cron_search_years
+const cron_search_years = 5
+
+func parseCron(s string) (*cronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, errors.New("schedule must be hourly, daily, weekly or a cron expression of 5 fields")
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronBounds[i][0], cronBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron field %q: %v", field, err)
+		}
+		bits[i] = b
+	}
+	c := &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	//Sunday is 0 or 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	if c.next(time.Now()).IsZero() {
+		return nil, errors.New("cron expression never matches")
+	}
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.New("bad step")
+			}
+			rng, step = item[:i], n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			if i := strings.IndexByte(rng, '-'); i >= 0 {
+				lo, err = strconv.Atoi(rng[:i])
+				if err == nil {
+					hi, err = strconv.Atoi(rng[i+1:])
+				}
+			} else {
+				lo, err = strconv.Atoi(rng)
+				if step == 1 {
+					hi = lo
+				}
+			}
+			if err != nil {
+				return 0, errors.New("bad number")
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("out of range %d-%d", min, max)
+		}
+		for n := lo; n <= hi; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	//like cron, either day matches if both are restricted
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+//next returns the zero time if no time matches in cron_search_years
+func (c *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(cron_search_years, 0, 0)
+	for t.Before(end) {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+//prev returns the zero time if no time matches in cron_search_years
+func (c *cronSchedule) prev(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute)
+	end := t.AddDate(-cron_search_years, 0, 0)
+	for t.After(end) {
+		y, m, d := t.Date()
+		//skip back to the last minute of the previous month, day or hour
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m, 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(-time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+//name is the start minute like 200601021504
+func (c *cronSchedule) name(t time.Time) string {
+	return t.Format("200601021504")
+}
@@ -0,0 +1,146 @@
+package log
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCalendarSchedule(t *testing.T) {
+	//Wednesday
+	at := time.Date(2020, 1, 1, 13, 45, 10, 0, time.Local)
+	cases := []struct {
+		schedule string
+		next     time.Time
+		prev     time.Time
+		name     string
+	}{
+		{"hourly", time.Date(2020, 1, 1, 14, 0, 0, 0, time.Local), time.Date(2020, 1, 1, 13, 0, 0, 0, time.Local), "2020010113"},
+		{"daily", time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local), time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local), "20200101"},
+		{"weekly", time.Date(2020, 1, 6, 0, 0, 0, 0, time.Local), time.Date(2019, 12, 30, 0, 0, 0, 0, time.Local), "2020-W01"},
+	}
+	for _, c := range cases {
+		s, err := parseSchedule(c.schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := s.next(at); !next.Equal(c.next) {
+			t.Errorf("%s next expect %v but is %v", c.schedule, c.next, next)
+		}
+		if prev := s.prev(at); !prev.Equal(c.prev) {
+			t.Errorf("%s prev expect %v but is %v", c.schedule, c.prev, prev)
+		}
+		if name := s.name(at); name != c.name {
+			t.Errorf("%s name expect %s but is %s", c.schedule, c.name, name)
+		}
+	}
+	//a boundary is not the next boundary of itself
+	monday := time.Date(2020, 1, 6, 0, 0, 0, 0, time.Local)
+	if next := calendarSchedule("weekly").next(monday); !next.Equal(monday.AddDate(0, 0, 7)) {
+		t.Error("weekly next of Monday is", next)
+	}
+}
+
+func TestCronSchedule(t *testing.T) {
+	at := time.Date(2020, 1, 1, 13, 45, 10, 0, time.Local)
+	cases := map[string]time.Time{
+		"* * * * *":         time.Date(2020, 1, 1, 13, 46, 0, 0, time.Local),
+		"0 */6 * * *":       time.Date(2020, 1, 1, 18, 0, 0, 0, time.Local),
+		"5/15 * * * *":      time.Date(2020, 1, 1, 13, 50, 0, 0, time.Local),
+		"30 2 * * 1-5":      time.Date(2020, 1, 2, 2, 30, 0, 0, time.Local),
+		"0 0 1 3 *":         time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local),
+		"0 0 * * 7":         time.Date(2020, 1, 5, 0, 0, 0, 0, time.Local),
+		"0 0 15 * 6":        time.Date(2020, 1, 4, 0, 0, 0, 0, time.Local),
+		"0,30 9-17/4 * * *": time.Date(2020, 1, 1, 17, 0, 0, 0, time.Local),
+	}
+	for expr, expect := range cases {
+		s, err := parseSchedule(expr)
+		if err != nil {
+			t.Errorf("parse %q error: %v", expr, err)
+			continue
+		}
+		if next := s.next(at); !next.Equal(expect) {
+			t.Errorf("%q next expect %v but is %v", expr, expect, next)
+		}
+	}
+	prevs := map[string]time.Time{
+		"* * * * *":         time.Date(2020, 1, 1, 13, 45, 0, 0, time.Local),
+		"0 6,12 * * *":      time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local),
+		"5/15 * * * *":      time.Date(2020, 1, 1, 13, 35, 0, 0, time.Local),
+		"30 2 * * 1-5":      time.Date(2020, 1, 1, 2, 30, 0, 0, time.Local),
+		"0 0 1 3 *":         time.Date(2019, 3, 1, 0, 0, 0, 0, time.Local),
+		"0 0 * * 7":         time.Date(2019, 12, 29, 0, 0, 0, 0, time.Local),
+		"0,30 9-17/4 * * *": time.Date(2020, 1, 1, 13, 30, 0, 0, time.Local),
+	}
+	for expr, expect := range prevs {
+		s, err := parseSchedule(expr)
+		if err != nil {
+			t.Errorf("parse %q error: %v", expr, err)
+			continue
+		}
+		if prev := s.prev(at); !prev.Equal(expect) {
+			t.Errorf("%q prev expect %v but is %v", expr, expect, prev)
+		}
+	}
+	for _, expr := range []string{"", "monthly", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "0 0 30 2 *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("parse %q expect error", expr)
+		}
+	}
+}
+
+func TestScheduledRotate(t *testing.T) {
+	dir := t.TempDir()
+	c := DefaultFileConfig()
+	c.Path = filepath.Join(dir, "app.log")
+	c.IsCompress = false
+	c.RotateSchedule = "daily"
+	hd, err := NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hd.Close()
+	h := hd.(*fileHandler)
+	day := time.Date(2020, 1, 2, 10, 0, 0, 0, time.Local)
+
+	for i := 0; i < 3; i++ {
+		//the file started on day and its day is over
+		h.mu.Lock()
+		h.setRotateTime(day)
+		h.mu.Unlock()
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "hi"})
+	}
+	for _, name := range []string{"app.log-20200102.log", "app.log-20200102.1.log", "app.log-20200102.2.log"} {
+		if _, exist := FileExists(filepath.Join(dir, name)); !exist {
+			t.Error(name, "is not rotated")
+		}
+	}
+	h.mu.Lock()
+	next := h.nextRotate
+	h.mu.Unlock()
+	if y, m, d := time.Now().AddDate(0, 0, 1).Date(); !next.Equal(time.Date(y, m, d, 0, 0, 0, 0, time.Local)) {
+		t.Error("next rotation is not the next midnight:", next)
+	}
+
+	//a file left by a previous process is named after the start of
+	//its period, not after its last write
+	c.RotateSchedule = "0 6,12 * * *"
+	hd, err = NewFileHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hd.Close()
+	h = hd.(*fileHandler)
+	h.mu.Lock()
+	h.setRotateTime(time.Date(2020, 1, 2, 11, 59, 0, 0, time.Local))
+	h.mu.Unlock()
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "hi"})
+	if _, exist := FileExists(filepath.Join(dir, "app.log-202001020600.log")); !exist {
+		t.Error("cron backup is not named after the start of its period")
+	}
+
+	c.RotateSchedule = "every day"
+	if _, err := NewFileHandler(c); err == nil {
+		t.Error("bad schedule expect error")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package log
+
+//HandleLevelSignals does nothing as there is no SIGUSR1/SIGUSR2
+func (l *LoggerImp) HandleLevelSignals() (stop func()) {
+	return func() {}
+}
+
+//HandleReopenSignal does nothing as there is no SIGHUP
+func (l *LoggerImp) HandleReopenSignal() (stop func()) {
+	return func() {}
+}
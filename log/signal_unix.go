@@ -0,0 +1,60 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+//HandleLevelSignals makes SIGUSR1 lower the logger level by one to log more
+//and SIGUSR2 raise it by one to log less, call stop to restore the signals
+func (l *LoggerImp) HandleLevelSignals() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				level := l.Level()
+				if sig == syscall.SIGUSR1 && level > lowestLevel {
+					l.changeLevel(level - 1)
+				} else if sig == syscall.SIGUSR2 && level < highestLevel {
+					l.changeLevel(level + 1)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(quit)
+	}
+}
+
+//HandleReopenSignal makes SIGHUP reopen the files of the handlers,
+//see Reopen, call stop to restore the signal
+func (l *LoggerImp) HandleReopenSignal() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				if err := l.Reopen(); err != nil {
+					l.Error("[log reopen] reopen log files err %s", err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(quit)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	logger := NewLogger().(*LoggerImp)
+	defer logger.Close(context.Background())
+	logger.SetLevel(InfoLevel)
+	stop := logger.HandleLevelSignals()
+	defer stop()
+
+	waitLevel := func(level int) {
+		deadline := time.Now().Add(time.Second)
+		for logger.Level() != level && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if logger.Level() != level {
+			t.Fatal("level expect", LevelName(level), "but is", LevelName(logger.Level()))
+		}
+	}
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitLevel(WarnLevel)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitLevel(InfoLevel)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitLevel(DebugLevel)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitLevel(TraceLevel)
+	//already the lowest level
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	time.Sleep(10 * time.Millisecond)
+	waitLevel(TraceLevel)
+}
+
+func TestHandleReopenSignal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	logger := newExternalLogger(t, path)
+	stop := logger.HandleReopenSignal()
+	defer stop()
+
+	logger.Info("a")
+	logger.Flush()
+	os.Rename(path, path+".1")
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, exist := FileExists(path); exist {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	logger.Info("b")
+	logger.Flush()
+	expectContent(t, path+".1", "a")
+	expectContent(t, path, "b")
+}
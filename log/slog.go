@@ -0,0 +1,292 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+//slog has no trace, fatal and panic levels, use the levels next to
+//debug and error, slog levels are 4 apart
+const (
+	slogLevelTrace = slog.LevelDebug - 4
+	slogLevelFatal = slog.LevelError + 4
+	slogLevelPanic = slog.LevelError + 8
+)
+
+func toSlogLevel(level int) slog.Level {
+	switch {
+	case level <= TraceLevel:
+		return slogLevelTrace
+	case level == DebugLevel:
+		return slog.LevelDebug
+	case level == InfoLevel:
+		return slog.LevelInfo
+	case level == WarnLevel:
+		return slog.LevelWarn
+	case level == ErrorLevel:
+		return slog.LevelError
+	case level == FatalLevel:
+		return slogLevelFatal
+	default:
+		return slogLevelPanic
+	}
+}
+
+func fromSlogLevel(level slog.Level) int {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	case level < slogLevelFatal:
+		return ErrorLevel
+	case level < slogLevelPanic:
+		return FatalLevel
+	default:
+		return PanicLevel
+	}
+}
+
+//slogHandler is a slog.Handler backed by the async LoggerImp
+//attrs in groups are flattened to fields with dotted keys, e.g. group.key
+type slogHandler struct {
+	logger *LoggerImp
+	fields []Field
+	prefix string
+}
+
+//NewSlogHandler returns a slog.Handler writing records to logger,
+//which must be created by NewLogger, e.g.
+//h, err := NewSlogHandler(logger); slog.New(h)
+func NewSlogHandler(logger Logger) (slog.Handler, error) {
+	l, ok := logger.(*LoggerImp)
+	if !ok {
+		return nil, ErrUnknownLogger
+	}
+	return &slogHandler{logger: l}, nil
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.moduleLevel() <= fromSlogLevel(level)
+}
+
+//Handle attaches the fields of ctx, see ContextWithFields
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := fromSlogLevel(r.Level)
+	if h.logger.moduleLevel() > level {
+		return nil
+	}
+	suppressed, ok := h.logger.sample(level, r.Message)
+	if !ok {
+		return nil
+	}
+	cf := FieldsFromContext(ctx)
+	fields := make([]Field, 0, len(h.fields)+len(cf)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	fields = append(fields, cf...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	lm := h.logger.newMesg(level, t, r.PC, r.Message, fields, nil)
+	lm.suppressed = suppressed
+	return h.logger.enqueue(lm)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]Field, 0, len(h.fields)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, a := range attrs {
+		fields = appendAttr(fields, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+func appendAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
+
+var errSlogSetLogger = errors.New("slog logger can not set log handler")
+
+//slogLogger is a Logger forwarding to a slog.Handler
+type slogLogger struct {
+	handler slog.Handler
+	level   *atomic.Int32
+	name    string
+}
+
+//NewSlogLogger returns a Logger writing to handler,
+//SetLogger and AddHandler are not supported and Flush/Close do nothing,
+//Fatal and Panic can not flush handler before exiting
+func NewSlogLogger(handler slog.Handler) Logger {
+	return &slogLogger{handler: handler, level: new(atomic.Int32)}
+}
+
+func (l *slogLogger) SetLogger(handlerType string, config map[string]interface{}) error {
+	return errSlogSetLogger
+}
+
+func (l *slogLogger) AddHandler(name string, handler Handler) error {
+	return errSlogSetLogger
+}
+
+func (l *slogLogger) SetLevel(level int) {
+	l.level.Store(int32(level))
+}
+
+func (l *slogLogger) With(kv ...interface{}) Logger {
+	return &slogLogger{
+		handler: l.handler.WithAttrs(fieldsToAttrs(argsToFields(kv))),
+		level:   l.level,
+		name:    l.name,
+	}
+}
+
+//Named adds the attr logger=name, as slog has no logger names
+func (l *slogLogger) Named(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return &slogLogger{
+		handler: l.handler.WithAttrs([]slog.Attr{slog.String("logger", name)}),
+		level:   l.level,
+		name:    name,
+	}
+}
+
+//log passes the fields of ctx as attrs, except to slogHandler
+//which reads them from ctx itself
+func (l *slogLogger) log(ctx context.Context, level int, format string, v []interface{}) {
+	if int(l.level.Load()) > level {
+		return
+	}
+	slevel := toSlogLevel(level)
+	if !l.handler.Enabled(ctx, slevel) {
+		return
+	}
+	mesg, fields := formatArgs(format, v)
+	//skip runtime.Callers, log and the exported method
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), slevel, mesg, pcs[0])
+	if _, ok := l.handler.(*slogHandler); !ok {
+		r.AddAttrs(fieldsToAttrs(FieldsFromContext(ctx))...)
+	}
+	r.AddAttrs(fieldsToAttrs(fields)...)
+	l.handler.Handle(ctx, r)
+}
+
+//Writer returns a writer logging every line written to it at level
+func (l *slogLogger) Writer(level int) io.Writer {
+	return newLineWriter(func(line string, pc uintptr) {
+		if int(l.level.Load()) > level {
+			return
+		}
+		ctx := context.Background()
+		slevel := toSlogLevel(level)
+		if !l.handler.Enabled(ctx, slevel) {
+			return
+		}
+		l.handler.Handle(ctx, slog.NewRecord(time.Now(), slevel, line, pc))
+	})
+}
+
+func fieldsToAttrs(fields []Field) []slog.Attr {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	return attrs
+}
+
+func (l *slogLogger) Trace(format string, v ...interface{}) {
+	l.log(context.Background(), TraceLevel, format, v)
+}
+
+func (l *slogLogger) Debug(format string, v ...interface{}) {
+	l.log(context.Background(), DebugLevel, format, v)
+}
+
+func (l *slogLogger) Info(format string, v ...interface{}) {
+	l.log(context.Background(), InfoLevel, format, v)
+}
+
+func (l *slogLogger) Warn(format string, v ...interface{}) {
+	l.log(context.Background(), WarnLevel, format, v)
+}
+
+func (l *slogLogger) Error(format string, v ...interface{}) {
+	l.log(context.Background(), ErrorLevel, format, v)
+}
+
+func (l *slogLogger) TraceContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, TraceLevel, format, v)
+}
+
+func (l *slogLogger) DebugContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, DebugLevel, format, v)
+}
+
+func (l *slogLogger) InfoContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, InfoLevel, format, v)
+}
+
+func (l *slogLogger) WarnContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, WarnLevel, format, v)
+}
+
+func (l *slogLogger) ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, ErrorLevel, format, v)
+}
+
+func (l *slogLogger) Fatal(format string, v ...interface{}) {
+	l.log(context.Background(), FatalLevel, format, v)
+	exit()
+}
+
+func (l *slogLogger) Panic(format string, v ...interface{}) {
+	l.log(context.Background(), PanicLevel, format, v)
+	mesg, _ := formatArgs(format, v)
+	panic(mesg)
+}
+
+func (l *slogLogger) Flush() error {
+	return nil
+}
+
+func (l *slogLogger) Close(ctx context.Context) error {
+	return nil
+}
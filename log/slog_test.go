@@ -0,0 +1,105 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	logger := NewLogger()
+	name := "test_slog.log"
+	defer os.Remove(name)
+	config := make(map[string]interface{})
+	config["path"] = name
+	config["format"] = "json"
+	if err := logger.SetLogger("file", config); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	logger.SetLevel(InfoLevel)
+
+	sh, err := NewSlogHandler(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSlogHandler(NewSlogLogger(sh)); err != ErrUnknownLogger {
+		t.Error("slog logger expect ErrUnknownLogger but", err)
+	}
+	sl := slog.New(sh)
+	sl.Debug("filtered")
+	sl.With("a", 1).WithGroup("g").Warn("warn", "k", 2, slog.Group("sub", "x", "y"))
+	sl.Log(context.Background(), slogLevelFatal, "fatal")
+	logger.Close(context.Background())
+
+	fd, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var m map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatal("invalid json line:", scanner.Text())
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 2 {
+		t.Fatal("line num of log file expect = 2 but is", len(lines))
+	}
+	if lines[0]["level"] != "WARN" || lines[0]["a"] != 1.0 ||
+		lines[0]["g.k"] != 2.0 || lines[0]["g.sub.x"] != "y" {
+		t.Error("log file error content:", lines[0])
+	}
+	if lines[1]["level"] != "FATAL" {
+		t.Error("log file error content:", lines[1])
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true})
+	logger := NewSlogLogger(handler)
+
+	logger.Debug("filtered by handler")
+	logger.With("req_id", 7).Warn("disk %d%%", 95, "path", "/data")
+	if err := logger.SetLogger("console", nil); err == nil {
+		t.Error("slog logger SetLogger expect error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatal("line num expect = 1 but is", len(lines), lines)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["level"] != "WARN" || m["msg"] != "disk 95%" ||
+		m["req_id"] != 7.0 || m["path"] != "/data" {
+		t.Error("slog logger error content:", lines[0])
+	}
+	source, _ := m["source"].(map[string]interface{})
+	if file, _ := source["file"].(string); !strings.HasSuffix(file, "slog_test.go") {
+		t.Error("slog logger source expect slog_test.go but is", source)
+	}
+}
+
+func TestSlogLoggerWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true}))
+	io.WriteString(logger.Writer(WarnLevel), "disk 90%\n")
+	io.WriteString(logger.Writer(DebugLevel), "skipped\n")
+	s := buf.String()
+	if !strings.Contains(s, `level=WARN`) || !strings.Contains(s, `msg="disk 90%"`) ||
+		!strings.Contains(s, "slog_test.go") || strings.Contains(s, "skipped") {
+		t.Error("slog output is", s)
+	}
+}
@@ -0,0 +1,310 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//syslog facilities, see RFC 5424 section 6.2.1
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+//local syslog sockets, the same as log/syslog
+var syslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+const (
+	syslog_dial_timeout  = 5 * time.Second
+	syslog_write_timeout = 5 * time.Second
+)
+
+//syslogSeverity maps the log level to the syslog severity
+func syslogSeverity(level int) int {
+	switch {
+	case level <= DebugLevel:
+		return 7 //debug
+	case level == InfoLevel:
+		return 6 //informational
+	case level == WarnLevel:
+		return 4 //warning
+	case level == ErrorLevel:
+		return 3 //error
+	default:
+		return 2 //critical
+	}
+}
+
+//syslogHandler sends messages to a syslog server
+//network is udp, tcp, unix or unixgram, empty means the local syslog socket
+//tcp, tcp4 and tcp6 use the octet counting framing of RFC 6587,
+//the server is dialed on the first message, and dialed again with
+//exponential backoff after a failure, the messages meanwhile are dropped
+type syslogHandler struct {
+	handlerLevel
+	mu         sync.Mutex
+	network    string
+	addr       string
+	rfc        int //5424 or 3164
+	facility   int
+	appName    string
+	hostname   string
+	pid        int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	conn       net.Conn
+	backoff    time.Duration
+	retryAt    time.Time
+	closed     bool
+}
+
+func newsyslogHandler() Handler {
+	return new(syslogHandler)
+}
+
+//NewSyslogHandler returns a syslog handler to add by AddHandler
+func NewSyslogHandler(c SyslogConfig) (Handler, error) {
+	h := new(syslogHandler)
+	if err := h.setup(c); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *syslogHandler) Setup(config map[string]interface{}) error {
+	c := DefaultSyslogConfig()
+	if err := DecodeConfig(config, &c); err != nil {
+		return err
+	}
+	return h.setup(c)
+}
+
+func (h *syslogHandler) setup(c SyslogConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	h.SetLevel(c.Level)
+	h.network = c.Network
+	h.addr = c.Addr
+	h.rfc = c.RFC
+	h.facility = c.Facility
+	h.appName = c.AppName
+	if h.appName == "" {
+		h.appName = filepath.Base(os.Args[0])
+	}
+	h.hostname = c.Hostname
+	if h.hostname == "" {
+		h.hostname, _ = os.Hostname()
+	}
+	h.pid = os.Getpid()
+	h.minBackoff = c.MinBackoff
+	h.maxBackoff = c.MaxBackoff
+	return nil
+}
+
+//toFacility accepts a facility number or name
+func toFacility(raw interface{}) (int, error) {
+	if name, ok := raw.(string); ok {
+		if n, ok := syslogFacilities[strings.ToLower(strings.TrimSpace(name))]; ok {
+			return n, nil
+		}
+	}
+	n, err := toInt64(raw)
+	if err != nil {
+		return 0, errors.New("Unknown syslog facility")
+	}
+	return int(n), nil
+}
+
+//connect returns true if connected, dials with exponential backoff,
+//caller must hold h.mu
+func (h *syslogHandler) connect() bool {
+	if h.conn != nil {
+		return true
+	}
+	now := time.Now()
+	if now.Before(h.retryAt) {
+		return false
+	}
+	if err := h.dial(); err != nil {
+		if h.backoff == 0 {
+			h.backoff = h.minBackoff
+		} else if h.backoff *= 2; h.backoff > h.maxBackoff {
+			h.backoff = h.maxBackoff
+		}
+		h.retryAt = now.Add(h.backoff)
+		return false
+	}
+	h.backoff = 0
+	return true
+}
+
+//dial dials the syslog server, caller must hold h.mu
+func (h *syslogHandler) dial() error {
+	if h.network != "" {
+		conn, err := net.DialTimeout(h.network, h.addr, syslog_dial_timeout)
+		if err != nil {
+			return err
+		}
+		h.conn = conn
+		return nil
+	}
+	for _, path := range syslogLocalPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, syslog_dial_timeout)
+			if err == nil {
+				h.conn = conn
+				return nil
+			}
+		}
+	}
+	return errors.New("Unix syslog delivery error")
+}
+
+func (h *syslogHandler) Write(lm *LogMesg) {
+	if !h.enabled(lm.Level) {
+		return
+	}
+	var buf bytes.Buffer
+	h.format(&buf, lm)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	//reconnect and retry once on failure, a stuck server times out,
+	//lm is dropped while the server is down
+	for i := 0; i < 2; i++ {
+		if !h.connect() {
+			return
+		}
+		h.conn.SetWriteDeadline(time.Now().Add(syslog_write_timeout))
+		if _, err := h.conn.Write(h.frame(buf.Bytes())); err == nil {
+			return
+		}
+		h.conn.Close()
+		h.conn = nil
+	}
+}
+
+//format writes the syslog message of lm
+func (h *syslogHandler) format(buf *bytes.Buffer, lm *LogMesg) {
+	pri := h.facility*8 + syslogSeverity(lm.Level)
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(pri))
+	buf.WriteByte('>')
+	if h.rfc == 5424 {
+		//<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+		buf.WriteString("1 ")
+		buf.WriteString(lm.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+		buf.WriteByte(' ')
+		buf.WriteString(syslogHeaderValue(h.hostname, 255))
+		buf.WriteByte(' ')
+		buf.WriteString(syslogHeaderValue(h.appName, 48))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Itoa(h.pid))
+		buf.WriteString(" - - ")
+	} else {
+		//<PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+		//the local syslog daemon adds the hostname itself
+		buf.WriteString(lm.Time.Format(time.Stamp))
+		buf.WriteByte(' ')
+		if h.network != "" {
+			buf.WriteString(syslogHeaderValue(h.hostname, 255))
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(h.appName)
+		buf.WriteByte('[')
+		buf.WriteString(strconv.Itoa(h.pid))
+		buf.WriteString("]: ")
+	}
+	if lm.Name != "" {
+		buf.WriteByte('[')
+		buf.WriteString(lm.Name)
+		buf.WriteString("] ")
+	}
+	buf.WriteString(lm.Mesg)
+	for _, field := range lm.Fields {
+		buf.WriteByte(' ')
+		writeTextString(buf, field.Key)
+		buf.WriteByte('=')
+		writeTextString(buf, textValue(field.Value))
+	}
+}
+
+//frame adds the transport framing to a message
+func (h *syslogHandler) frame(mesg []byte) []byte {
+	switch {
+	case strings.HasPrefix(h.network, "tcp"):
+		//octet counting, RFC 6587 section 3.4.1
+		return append([]byte(strconv.Itoa(len(mesg))+" "), mesg...)
+	case h.network == "unix":
+		return append(mesg, '\n')
+	default:
+		return mesg
+	}
+}
+
+//syslogHeaderValue returns "-" for empty values and
+//replaces the chars not allowed in RFC 5424 header fields
+func syslogHeaderValue(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+func (h *syslogHandler) Rotate() {
+	//do nothing
+}
+
+func (h *syslogHandler) Flush() error {
+	return nil
+}
+
+func (h *syslogHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
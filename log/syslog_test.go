@@ -0,0 +1,265 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	logger := NewLogger()
+	config := make(map[string]interface{})
+	config["network"] = "udp"
+	config["addr"] = pc.LocalAddr().String()
+	config["facility"] = "local0"
+	config["appName"] = "app"
+	config["hostname"] = "host"
+	if err := logger.SetLogger("syslog", config); err != nil {
+		t.Fatal("set logger error:", err)
+	}
+	logger.Warn("disk full", "path", "/data")
+	logger.Close(context.Background())
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//local0*8 + warning
+	pattern := `^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ host app ` +
+		strconv.Itoa(os.Getpid()) + ` - - disk full path=/data$`
+	if !regexp.MustCompile(pattern).Match(buf[:n]) {
+		t.Error("syslog message error:", string(buf[:n]))
+	}
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	mesgs := make(chan string, 16)
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				//octet counting framing: LEN SP MSG
+				size, err := r.ReadString(' ')
+				if err != nil {
+					break
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(size))
+				mesg := make([]byte, n)
+				if _, err := io.ReadFull(r, mesg); err != nil {
+					break
+				}
+				mesgs <- string(mesg)
+				if i == 0 {
+					//drop the first connection to force a reconnect
+					break
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	h := newsyslogHandler()
+	config := make(map[string]interface{})
+	config["network"] = "tcp"
+	config["addr"] = ln.Addr().String()
+	config["rfc"] = 3164
+	config["appName"] = "app"
+	config["hostname"] = "host"
+	if err := h.Setup(config); err != nil {
+		t.Fatal("setup syslog error:", err)
+	}
+	defer h.Close()
+
+	h.Write(&LogMesg{Level: ErrorLevel, Time: time.Now(), Mesg: "first"})
+	select {
+	case mesg := <-mesgs:
+		if !regexp.MustCompile(`^<11>\w{3} [ \d]\d \d\d:\d\d:\d\d host app\[\d+\]: first$`).MatchString(mesg) {
+			t.Error("syslog message error:", mesg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("first message is not received")
+	}
+
+	//writes to the closed connection fail after a while, then reconnect
+	deadline := time.After(5 * time.Second)
+	for {
+		h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "again"})
+		select {
+		case mesg := <-mesgs:
+			if !strings.HasSuffix(mesg, "again") {
+				t.Error("syslog message error:", mesg)
+			}
+			return
+		case <-deadline:
+			t.Fatal("message is not received after reconnect")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestSyslogWriteAfterClose(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	h := newsyslogHandler()
+	config := map[string]interface{}{"network": "tcp4", "addr": ln.Addr().String()}
+	if err := h.Setup(config); err != nil {
+		t.Fatal("setup syslog error:", err)
+	}
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "hello"})
+	conn := <-conns
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	size, err := bufio.NewReader(conn).ReadString(' ')
+	if _, e := strconv.Atoi(strings.TrimSuffix(size, " ")); err != nil || e != nil {
+		t.Error("tcp4 expect octet counting framing but", size, err)
+	}
+
+	h.Close()
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "after close"})
+	select {
+	case c := <-conns:
+		c.Close()
+		t.Error("write after close redials")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSyslogBackoff(t *testing.T) {
+	//reserve a port with no server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	h := newsyslogHandler()
+	config := map[string]interface{}{"network": "tcp", "addr": addr, "minBackoff": 200 * time.Millisecond}
+	if err := h.Setup(config); err != nil {
+		t.Fatal("setup syslog expect no dial but", err)
+	}
+	defer h.Close()
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "dropped"})
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("can not listen on the reserved port again:", err)
+	}
+	defer ln.Close()
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "backoff"})
+	select {
+	case conn := <-conns:
+		conn.Close()
+		t.Fatal("dial again before the backoff passes")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	h.Write(&LogMesg{Level: InfoLevel, Time: time.Now(), Mesg: "again"})
+	select {
+	case conn := <-conns:
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 1024)
+		n, _ := conn.Read(buf)
+		if mesg := string(buf[:n]); !strings.HasSuffix(mesg, "again") || strings.Contains(mesg, "dropped") {
+			t.Error("syslog message error:", mesg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no dial after the backoff")
+	}
+}
+
+func TestSyslogUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip("unixgram not supported:", err)
+	}
+	defer conn.Close()
+
+	h := newsyslogHandler()
+	config := make(map[string]interface{})
+	config["network"] = "unixgram"
+	config["addr"] = path
+	config["level"] = InfoLevel
+	if err := h.Setup(config); err != nil {
+		t.Fatal("setup syslog error:", err)
+	}
+	defer h.Close()
+	h.Write(&LogMesg{Level: DebugLevel, Time: time.Now(), Mesg: "filtered"})
+	h.Write(&LogMesg{Level: FatalLevel, Time: time.Now(), Mesg: "fatal"})
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//user*8 + critical
+	if mesg := string(buf[:n]); !strings.HasPrefix(mesg, "<10>1 ") || !strings.HasSuffix(mesg, " fatal") {
+		t.Error("syslog message error:", mesg)
+	}
+}
+
+func TestSyslogConfigError(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"network": "sctp", "addr": "127.0.0.1:514"},
+		{"network": "udp"},
+		{"network": "udp", "addr": "127.0.0.1:514", "rfc": 1234},
+		{"network": "udp", "addr": "127.0.0.1:514", "facility": "nope"},
+	} {
+		if err := newsyslogHandler().Setup(config); err == nil {
+			t.Error("setup syslog expect error with config", config)
+		}
+	}
+}